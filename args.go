@@ -0,0 +1,162 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"slices"
+	"strconv"
+)
+
+// argRest binds the remaining positional arguments to a slice, e.g. `arg:"rest"`.
+const argRest = "rest"
+
+// boundArg is a positional argument bound to a field via its arg tag.
+type boundArg struct {
+	index    int    // -1 for argRest
+	rest     bool   // see argRest
+	name     string // flag name the field would have, shown as <name> in errors
+	field    string // for errors
+	value    pflag.Value
+	required bool
+	secret   bool
+}
+
+// argFlags returns the flag set that parses the values of positional arguments. It is not part of
+// the command, so that arguments get the conversions of flags without becoming flags.
+func (b *binder) argFlags() *pflag.FlagSet {
+	if b.argSet == nil {
+		b.argSet = pflag.NewFlagSet("args", pflag.ContinueOnError)
+	}
+	return b.argSet
+}
+
+// addArg binds param, which was added to argFlags, to the positional argument of its arg tag.
+func (b *binder) addArg(fieldName string, meta fieldMeta, tags fieldTags, opts fieldOpts, param *pflag.Flag) error {
+	index, rest := -1, tags.arg == argRest
+	if rest {
+		if _, ok := param.Value.(pflag.SliceValue); !ok {
+			return b.errorf(fieldName, `arg:"rest" for %q requires a slice, got %s`, tags.name, param.Value.Type())
+		}
+	} else if i, err := strconv.Atoi(tags.arg); err == nil && i >= 0 {
+		index = i
+	} else {
+		return b.errorf(fieldName, `expected arg:"<index>" or arg:"rest" for %q, got %q`, tags.name, tags.arg)
+	}
+	switch {
+	case tags.abbrev != "":
+		return b.errorf(fieldName, "argument %q must not have a shorthand", tags.name)
+	case opts.persistent:
+		return b.errorf(fieldName, "argument %q must not be persistent", tags.name)
+	case tags.hasOption(optNegatable) || tags.flagAliases != nil || tags.groups != nil:
+		return b.errorf(fieldName, "argument %q must not be negatable or have aliases or groups", tags.name)
+	case meta.tags.env != "":
+		return b.errorf(fieldName, "argument %q must not have an env tag", tags.name)
+	}
+	if i := slices.IndexFunc(b.args, func(arg boundArg) bool { return arg.index == index }); i != -1 {
+		return b.errorf(fieldName, "argument %s is already bound to %s", tags.arg, b.args[i].field)
+	}
+	b.trace("%s: bound to argument %s <%s>", fieldName, tags.arg, tags.name)
+	b.args = append(b.args, boundArg{
+		index:    index,
+		rest:     rest,
+		name:     tags.name,
+		field:    fieldName,
+		value:    param.Value,
+		required: opts.required,
+		secret:   opts.secret,
+	})
+	return nil
+}
+
+// bindArgs makes cmd parse its positional arguments into the fields bound via arg tags. Unless cmd
+// has an Args validator, it accepts the required arguments up to all bound ones, or any number
+// beyond the required ones if the remaining arguments are bound.
+func (b *binder) bindArgs() error {
+	if len(b.args) == 0 {
+		return nil
+	}
+	var args []boundArg
+	var rest *boundArg
+	for _, arg := range b.args {
+		if arg.rest {
+			rest = &arg
+		} else {
+			args = append(args, arg)
+		}
+	}
+	slices.SortFunc(args, func(a, b boundArg) int { return a.index - b.index })
+	required := 0
+	for i, arg := range args {
+		if arg.index != i {
+			return b.errorf(arg.field, "argument %d of %q leaves a gap after argument %d", arg.index, arg.name, i-1)
+		}
+		if arg.required {
+			if required != i {
+				return b.errorf(arg.field, "required argument %q follows an optional one", arg.name)
+			}
+			required = i + 1
+		}
+	}
+	if rest != nil && rest.required {
+		if required != len(args) {
+			return b.errorf(rest.field, "required argument %q follows an optional one", rest.name)
+		}
+		required++
+	}
+	validate := b.cmd.Args
+	switch {
+	case validate != nil:
+	case rest != nil:
+		validate = cobra.MinimumNArgs(required)
+	default:
+		validate = cobra.RangeArgs(required, len(args))
+	}
+	b.cmd.Args = func(cmd *cobra.Command, values []string) error {
+		if err := validate(cmd, values); err != nil {
+			return err
+		}
+		for i, arg := range args {
+			if i >= len(values) {
+				return checkMissing(arg)
+			}
+			if err := arg.value.Set(values[i]); err != nil {
+				return arg.error(values[i], err)
+			}
+		}
+		if rest == nil {
+			return nil
+		}
+		if len(values) <= len(args) {
+			return checkMissing(*rest)
+		}
+		// Each value on its own, so that errors name the invalid one
+		list := rest.value.(pflag.SliceValue)
+		if err := list.Replace(nil); err != nil {
+			return err
+		}
+		for _, value := range values[len(args):] {
+			if err := list.Append(value); err != nil {
+				return rest.error(value, err)
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// checkMissing returns an error if arg is required. It is called for args that were not given.
+func checkMissing(arg boundArg) error {
+	if arg.required {
+		return fmt.Errorf(DefaultMessages.MissingArg, arg.name)
+	}
+	return nil
+}
+
+// error returns the error for an invalid value of arg.
+func (arg boundArg) error(value string, err error) error {
+	if arg.secret {
+		return fmt.Errorf(DefaultMessages.InvalidSecretArg, arg.name)
+	}
+	return fmt.Errorf(DefaultMessages.InvalidArg, value, arg.name, err)
+}
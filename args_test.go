@@ -0,0 +1,163 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type copyConf struct {
+	Verbose bool
+	Source  string `arg:"0" flag:"required"`
+	Port    int    `arg:"1" min:"1"`
+	Host    net.IP `arg:"2" default:"127.0.0.1"`
+	Token   string `arg:"3" flag:"secret" pattern:"^[a-z]+$"`
+}
+
+func executeArgs(t *testing.T, args ...string) (copyConf, error) {
+	t.Helper()
+	var got copyConf
+	run := Run(func(cfg copyConf, cmd *cobra.Command, args []string) error {
+		got = cfg
+		return nil
+	})
+	cmd, err := TryCommand("TEST", run, cobra.Command{Use: "test"}, copyConf{Port: 22}, WithEnviron(nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd.SetArgs(args)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	return got, cmd.Execute()
+}
+
+func TestArgs(t *testing.T) {
+	got, err := executeArgs(t, "src", "--verbose", "2222")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Source != "src" || got.Port != 2222 || got.Host.String() != "127.0.0.1" || !got.Verbose {
+		t.Errorf("unexpected configuration %+v", got)
+	}
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{args: nil, want: "accepts between 1 and 4 arg(s), received 0"},
+		{args: []string{"a", "b", "c", "d", "e"}, want: "accepts between 1 and 4 arg(s), received 5"},
+		{args: []string{"src", "ssh"}, want: `invalid argument "ssh" for <port>: strconv.ParseInt: parsing "ssh": invalid syntax`},
+		{args: []string{"src", "0"}, want: `invalid argument "0" for <port>: must be at least 1`},
+		{args: []string{"src", "1", "localhost"}, want: `invalid argument "localhost" for <host>`},
+		{args: []string{"src", "1", "::1", "HUNTER2"}, want: "invalid argument <redacted> for <token>"},
+	} {
+		_, err := executeArgs(t, tc.args...)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+			t.Errorf("args %q: expected error %q, got %v", tc.args, tc.want, err)
+		}
+	}
+}
+
+func TestArgs_Validator(t *testing.T) {
+	var cfg struct {
+		Name string `arg:"0" flag:"required"`
+	}
+	cmd := &cobra.Command{Use: "test", Args: cobra.ArbitraryArgs, Run: func(*cobra.Command, []string) {}}
+	if err := TryBindConfig("TEST", cmd, &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cmd.Args(cmd, []string{"a", "b"}); err != nil || cfg.Name != "a" {
+		t.Errorf("expected the custom validator to allow further args, got %v and %q", err, cfg.Name)
+	}
+	if err := cmd.Args(cmd, nil); err == nil || err.Error() != "missing argument <name>" {
+		t.Errorf("expected error for missing required argument, got %v", err)
+	}
+	if cmd.Flags().Lookup("name") != nil || len(Fields(cmd)) != 0 {
+		t.Error("expected argument not to be bound to a flag")
+	}
+}
+
+func TestArgs_Rest(t *testing.T) {
+	var cfg struct {
+		Mode     string          `arg:"0" flag:"required"`
+		Timeouts []time.Duration `arg:"rest" flag:"required"`
+	}
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	if err := TryBindConfig("TEST", cmd, &cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cmd.Args(cmd, []string{"ping", "1s", "1m"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Mode != "ping" || len(cfg.Timeouts) != 2 || cfg.Timeouts[1] != time.Minute {
+		t.Errorf("unexpected configuration %+v", cfg)
+	}
+	if err := cmd.Args(cmd, []string{"ping"}); err == nil || err.Error() != "requires at least 2 arg(s), only received 1" {
+		t.Errorf("expected error for missing timeouts, got %v", err)
+	}
+	err := cmd.Args(cmd, []string{"ping", "1s", "forever", "2s"})
+	if err == nil || !strings.HasPrefix(err.Error(), `invalid argument "forever" for <timeouts>`) {
+		t.Errorf("expected error for the invalid timeout, got %v", err)
+	}
+
+	var optional struct {
+		Ports []int `arg:"rest"`
+	}
+	optional.Ports = []int{80}
+	cmd = &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	if err := TryBindConfig("TEST", cmd, &optional); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cmd.Args(cmd, nil); err != nil || len(optional.Ports) != 1 {
+		t.Errorf("expected the default without args, got %v and %v", err, optional.Ports)
+	}
+	if err := cmd.Args(cmd, []string{"8080", "8443"}); err != nil || len(optional.Ports) != 2 || optional.Ports[0] != 8080 {
+		t.Errorf("expected args to replace the default, got %v and %v", err, optional.Ports)
+	}
+}
+
+func TestArgs_Invalid(t *testing.T) {
+	var bindErr *BindError
+	for name, conf := range map[string]any{
+		"not an index": &struct {
+			A string `arg:"first"`
+		}{},
+		"gap": &struct {
+			A string `arg:"0"`
+			B string `arg:"2"`
+		}{},
+		"duplicate": &struct {
+			A string `arg:"0"`
+			B string `arg:"0"`
+		}{},
+		"required after optional": &struct {
+			A string `arg:"0"`
+			B string `arg:"1" flag:"required"`
+		}{},
+		"env": &struct {
+			A string `arg:"0" env:"FOO"`
+		}{},
+		"shorthand": &struct {
+			A string `arg:"0" param:"a"`
+		}{},
+		"rest without slice": &struct {
+			A string `arg:"rest"`
+		}{},
+		"required rest after optional": &struct {
+			A string   `arg:"0"`
+			B []string `arg:"rest" flag:"required"`
+		}{},
+		"struct": &struct {
+			A struct{ B string } `arg:"0"`
+		}{},
+	} {
+		err := TryBindConfig("TEST", &cobra.Command{Use: "test"}, conf)
+		if !errors.As(err, &bindErr) {
+			t.Errorf("%s: expected *BindError, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package nicecmd
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ByteSize is a number of bytes that is given with an optional unit, e.g. "512", "10MB" or
+// "1.5GiB". SI units (kB, MB, GB, TB, PB, EB) are powers of 1000, and IEC units (KiB, MiB, GiB,
+// TiB, PiB, EiB) powers of 1024. Units are case-insensitive. Use it for fields like MaxUploadSize.
+//
+// A ByteSize is formatted with the largest unit that gives a whole number, e.g. "10MB" or "1GiB".
+type ByteSize uint64
+
+// Multiples of ByteSize.
+const (
+	Byte ByteSize = 1
+
+	KB ByteSize = 1000 * Byte
+	MB ByteSize = 1000 * KB
+	GB ByteSize = 1000 * MB
+	TB ByteSize = 1000 * GB
+	PB ByteSize = 1000 * TB
+	EB ByteSize = 1000 * PB
+
+	KiB ByteSize = 1024 * Byte
+	MiB ByteSize = 1024 * KiB
+	GiB ByteSize = 1024 * MiB
+	TiB ByteSize = 1024 * GiB
+	PiB ByteSize = 1024 * TiB
+	EiB ByteSize = 1024 * PiB
+)
+
+// byteUnits lists the units of ByteSize from the largest to the smallest.
+var byteUnits = []struct {
+	name string
+	size ByteSize
+}{
+	{"EiB", EiB}, {"EB", EB}, {"PiB", PiB}, {"PB", PB}, {"TiB", TiB}, {"TB", TB},
+	{"GiB", GiB}, {"GB", GB}, {"MiB", MiB}, {"MB", MB}, {"KiB", KiB}, {"kB", KB}, {"B", Byte},
+}
+
+func (s ByteSize) String() string {
+	for _, unit := range byteUnits {
+		if s != 0 && s%unit.size == 0 {
+			return strconv.FormatUint(uint64(s/unit.size), 10) + unit.name
+		}
+	}
+	return "0B"
+}
+
+func (s *ByteSize) UnmarshalText(text []byte) error {
+	str := strings.TrimSpace(string(text))
+	end := strings.IndexFunc(str, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if end == -1 {
+		end = len(str)
+	}
+	num, name := str[:end], strings.TrimSpace(str[end:])
+	unit := Byte
+	if name != "" {
+		i := -1
+		for j, u := range byteUnits {
+			if strings.EqualFold(u.name, name) {
+				i = j
+				break
+			}
+		}
+		if i == -1 {
+			return fmt.Errorf("invalid size %q: unknown unit %q, expected e.g. 512, 10MB or 1.5GiB", str, name)
+		}
+		unit = byteUnits[i].size
+	}
+	r, ok := new(big.Rat).SetString(num)
+	if num == "" || !ok {
+		return fmt.Errorf("invalid size %q, expected e.g. 512, 10MB or 1.5GiB", str)
+	}
+	r.Mul(r, new(big.Rat).SetUint64(uint64(unit)))
+	if !r.IsInt() {
+		return fmt.Errorf("invalid size %q: not a whole number of bytes", str)
+	}
+	if !r.Num().IsUint64() {
+		return fmt.Errorf("invalid size %q: too large", str)
+	}
+	*s = ByteSize(r.Num().Uint64())
+	return nil
+}
+
+func (s ByteSize) CmdTypeDesc() string {
+	return "size"
+}
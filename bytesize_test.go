@@ -0,0 +1,69 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"strings"
+	"testing"
+)
+
+func TestByteSize(t *testing.T) {
+	for in, want := range map[string]ByteSize{
+		"0":        0,
+		"512":      512,
+		"10MB":     10 * MB,
+		"10 mb":    10 * MB,
+		"1kB":      KB,
+		"1KB":      KB,
+		"1.5GiB":   1536 * MiB,
+		"2tib":     2 * TiB,
+		"1.5KB":    1500,
+		"1024KiB ": MiB,
+	} {
+		var got ByteSize
+		if err := got.UnmarshalText([]byte(in)); err != nil || got != want {
+			t.Errorf("%q: expected %d, got %d, %v", in, want, got, err)
+		}
+	}
+	for _, in := range []string{"", "MB", "-1", "10XB", "1.2.3MB", "1.5B", "0x10", "16EiB"} {
+		var got ByteSize
+		if err := got.UnmarshalText([]byte(in)); err == nil {
+			t.Errorf("%q: expected error, got %d", in, got)
+		}
+	}
+	for size, want := range map[ByteSize]string{
+		0:            "0B",
+		1:            "1B",
+		1536:         "1536B",
+		KB:           "1kB",
+		1000 * KiB:   "1000KiB",
+		10 * MB:      "10MB",
+		1536 * MiB:   "1536MiB",
+		GiB:          "1GiB",
+		3 * EiB:      "3EiB",
+		1234567 * KB: "1234567kB",
+	} {
+		if got := size.String(); got != want {
+			t.Errorf("%d: expected %q, got %q", uint64(size), want, got)
+		}
+	}
+}
+
+func TestByteSize_Flag(t *testing.T) {
+	type Conf struct {
+		MaxUpload ByteSize `max:"1GiB"`
+	}
+	cfg := Conf{MaxUpload: 10 * MB}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_MAX_UPLOAD=512MiB"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaxUpload != 512*MiB {
+		t.Errorf("expected 512MiB from the environment, got %s", cfg.MaxUpload)
+	}
+	if usage := cmd.Flags().FlagUsages(); !strings.Contains(usage, "--max-upload size") || !strings.Contains(usage, "(default 10MB)") {
+		t.Errorf("expected size flag with default 10MB, got:\n%s", usage)
+	}
+	if err := cmd.ParseFlags([]string{"--max-upload", "2GB"}); err == nil || !strings.Contains(err.Error(), "must be at most 1GiB") {
+		t.Errorf("expected error for size above max, got %v", err)
+	}
+}
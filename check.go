@@ -0,0 +1,149 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"slices"
+	"strings"
+)
+
+// Problem describes an inconsistency in a command tree found by Check.
+type Problem struct {
+	Command *cobra.Command
+	Flag    string // name of the offending flag, empty if the problem concerns the command
+	Msg     string
+}
+
+func (p Problem) String() string {
+	if p.Flag == "" {
+		return fmt.Sprintf("%s: %s", p.Command.CommandPath(), p.Msg)
+	}
+	return fmt.Sprintf("%s: --%s: %s", p.Command.CommandPath(), p.Flag, p.Msg)
+}
+
+// Check validates a fully built command tree and returns all problems found, in a deterministic
+// order. It is meant to be called from a unit test, so that a single test guards the whole CLI:
+//
+//	func TestCLI(t *testing.T) {
+//		for _, p := range nicecmd.Check(newRootCmd()) {
+//			t.Error(p)
+//		}
+//	}
+func Check(root *cobra.Command) []Problem {
+	c := &checker{envs: make(map[string][]envUse)}
+	c.visit(root)
+	return c.problems
+}
+
+type checker struct {
+	problems []Problem
+	envs     map[string][]envUse
+}
+
+// envUse remembers a flag that was seen for an environment variable.
+type envUse struct {
+	cmd  *cobra.Command
+	flag *pflag.Flag
+}
+
+func (c *checker) report(cmd *cobra.Command, flag string, format string, args ...any) {
+	c.problems = append(c.problems, Problem{Command: cmd, Flag: flag, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (c *checker) visit(cmd *cobra.Command) {
+	if cmd.Use == "" {
+		c.report(cmd, "", "use line is not set")
+	}
+
+	for _, flag := range ownFlags(cmd) {
+		persistent := cmd.PersistentFlags().Lookup(flag.Name)
+		if local := cmd.Flags().Lookup(flag.Name); persistent != nil && local != nil && persistent != local {
+			if flag == persistent {
+				c.report(cmd, flag.Name, "flag is defined both as local and as persistent flag")
+			}
+		}
+
+		if owner := persistentOwner(cmd.Parent(), flag.Name); owner != nil {
+			c.report(cmd, flag.Name, "flag shadows the persistent flag of %q", owner.CommandPath())
+		}
+
+		if isSecret(flag) && showsDefault(flag) {
+			c.report(cmd, flag.Name, "secret flag shows its default value in help")
+		}
+
+		if isRequired(flag) && persistent != flag && !cmd.Runnable() {
+			c.report(cmd, flag.Name, "required local flag on a command that cannot run")
+		}
+
+		for _, env := range flag.Annotations[annotationEnv] {
+			// Sibling commands may share a variable, as only one of them runs at a time
+			i := slices.IndexFunc(c.envs[env], func(prev envUse) bool {
+				return isAncestor(prev.cmd, cmd)
+			})
+			if i != -1 {
+				prev := c.envs[env][i]
+				c.report(cmd, flag.Name, "environment variable %s is already bound to --%s of %q",
+					env, prev.flag.Name, prev.cmd.CommandPath())
+			} else {
+				c.envs[env] = append(c.envs[env], envUse{cmd: cmd, flag: flag})
+			}
+		}
+	}
+
+	for _, sub := range cmd.Commands() {
+		c.visit(sub)
+	}
+}
+
+// isAncestor reports whether a is cmd or one of its parents.
+func isAncestor(a, cmd *cobra.Command) bool {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd == a {
+			return true
+		}
+	}
+	return false
+}
+
+// ownFlags returns the flags defined on cmd itself, local and persistent, sorted by name.
+func ownFlags(cmd *cobra.Command) (flags []*pflag.Flag) {
+	inherited := make(map[*pflag.Flag]bool)
+	for p := cmd.Parent(); p != nil; p = p.Parent() {
+		p.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+			inherited[flag] = true
+		})
+	}
+	add := func(flag *pflag.Flag) {
+		if !inherited[flag] && !isBindingFlag(flag) && !slices.Contains(flags, flag) {
+			flags = append(flags, flag)
+		}
+	}
+	cmd.Flags().VisitAll(add)
+	cmd.PersistentFlags().VisitAll(add)
+	slices.SortStableFunc(flags, func(a, b *pflag.Flag) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return
+}
+
+// persistentOwner returns the closest command starting at cmd that defines a persistent flag name.
+func persistentOwner(cmd *cobra.Command, name string) *cobra.Command {
+	for ; cmd != nil; cmd = cmd.Parent() {
+		if cmd.PersistentFlags().Lookup(name) != nil {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// showsDefault reports whether pflag would print the flag's default value in its usage.
+func showsDefault(flag *pflag.Flag) bool {
+	fs := pflag.NewFlagSet("", pflag.ContinueOnError)
+	fs.AddFlag(flag)
+	return strings.Contains(fs.FlagUsages(), "(default ")
+}
+
+func isRequired(flag *pflag.Flag) bool {
+	return slices.Contains(flag.Annotations[cobra.BashCompOneRequiredFlag], "true")
+}
@@ -0,0 +1,94 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	type RootConf struct {
+		Verbose bool   `flag:"persistent"`
+		Name    string `flag:"required"`
+	}
+	type SubConf struct {
+		Name string `env:"TEST_NAME"`
+	}
+	noop := func(cfg RootConf, cmd *cobra.Command, args []string) error { return nil }
+	subNoop := func(cfg SubConf, cmd *cobra.Command, args []string) error { return nil }
+
+	root := Command("TEST", PersistentPreRun(noop), cobra.Command{Use: "root"}, RootConf{})
+	root.Flags().Bool("verbose", false, "duplicate of the persistent flag")
+	root.AddCommand(Command("TEST_SUB", Run(subNoop), cobra.Command{Use: "sub"}, SubConf{}))
+	root.AddCommand(&cobra.Command{Run: func(cmd *cobra.Command, args []string) {}})
+
+	var got []string
+	for _, p := range Check(root) {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"root: --name: required local flag on a command that cannot run",
+		"root: --verbose: flag is defined both as local and as persistent flag",
+		"root : use line is not set",
+		`root sub: --name: environment variable TEST_NAME is already bound to --name of "root"`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected problems:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+func TestCheck_Clean(t *testing.T) {
+	root := Command("TEST", Run(trivialRun), cobra.Command{Use: "root"}, TrivialConf{})
+	if problems := Check(root); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestCheck_Shadowing(t *testing.T) {
+	type RootConf struct {
+		Verbose bool `flag:"persistent"`
+	}
+	type SubConf struct {
+		Verbose bool
+	}
+	root := Command("TEST", Run(func(cfg RootConf, cmd *cobra.Command, args []string) error {
+		return nil
+	}), cobra.Command{Use: "root"}, RootConf{})
+	root.AddCommand(Command("TEST_SUB", Run(func(cfg SubConf, cmd *cobra.Command, args []string) error {
+		return nil
+	}), cobra.Command{Use: "sub"}, SubConf{}))
+
+	problems := Check(root)
+	want := `root sub: --verbose: flag shadows the persistent flag of "root"`
+	if len(problems) != 1 || problems[0].String() != want {
+		t.Errorf("expected problem %q, got %v", want, problems)
+	}
+}
+
+func TestCheck_SecretDefault(t *testing.T) {
+	var conf struct {
+		Token  string `flag:"secret"`
+		Hidden string `flag:"secret"`
+	}
+	conf.Token = "hunter2"
+	cmd := &cobra.Command{Use: "test", Run: func(cmd *cobra.Command, args []string) {}}
+	BindConfig("TEST", cmd, &conf)
+	problems := Check(cmd)
+	want := "test: --token: secret flag shows its default value in help"
+	if len(problems) != 1 || problems[0].String() != want {
+		t.Errorf("expected problem %q, got %v", want, problems)
+	}
+}
+
+func TestCheck_SiblingEnv(t *testing.T) {
+	root, err := New("TEST", cobra.Command{Use: "root"}, struct {
+		A struct{ Port int } `cmd:"a"`
+		B struct{ Port int } `cmd:"b"`
+	}{}, WithFlatEnv())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if problems := Check(root); len(problems) != 0 {
+		t.Errorf("expected sibling commands to share variables, got %v", problems)
+	}
+}
@@ -0,0 +1,135 @@
+package nicecmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mologie/nicecmd/internal/bridge"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"reflect"
+)
+
+func init() {
+	bridge.Clone = func(root *cobra.Command, env map[string]string, stdout, stderr io.Writer) (*cobra.Command, error) {
+		clone, envErrs, err := cloneTree(root, mapEnv(env), stdout, stderr)
+		if err != nil {
+			return nil, err
+		}
+		return clone, joinEnvErrors(envErrs)
+	}
+	bridge.Config = func(cmd *cobra.Command) (any, bool) {
+		if b := lookupBinding(cmd); b != nil {
+			return reflect.ValueOf(b.cfg).Elem().Interface(), true
+		}
+		return nil, false
+	}
+	bridge.PersistentHooks = func(cmd *cobra.Command) (pre, post *func(cmd *cobra.Command, args []string) error) {
+		if b := lookupBinding(cmd); b != nil && b.hooked {
+			return &b.persistentPreRun, &b.persistentPostRun
+		}
+		return &cmd.PersistentPreRunE, &cmd.PersistentPostRunE
+	}
+}
+
+// Clone rebuilds the command tree root with new configuration instances and fresh flag state, as
+// if all of its commands were created again by Command, with the same templates and defaults. Use
+// it to execute a tree more than once, e.g. in parallel tests or from a long-running server.
+//
+// Environment variables are taken from the process environment, or from the environment given via
+// WithEnviron or WithLookupEnv. Like TryCommand, Clone returns the
+// tree along with joined *EnvError if any of them are invalid. Changes made to the commands after
+// they were created, e.g. via SetOut, are not carried over.
+func Clone(root *cobra.Command) (*cobra.Command, error) {
+	clone, envErrs, err := cloneTree(root, envSource{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return clone, joinEnvErrors(envErrs)
+}
+
+// binding is what nicecmd remembers about each command that it bound a configuration to.
+type binding struct {
+	cfg      any // pointer to the bound configuration struct
+	fields   []FieldInfo
+	config   configOptions
+	expander *expander // for configuration files, nil unless WithExpansion is given
+	files    fileSystem
+
+	// hooked is set for commands created by Command, whose persistent hooks run those of all of
+	// their parents. persistentPreRun and persistentPostRun are their own hooks, or nil.
+	hooked            bool
+	persistentPreRun  func(cmd *cobra.Command, args []string) error
+	persistentPostRun func(cmd *cobra.Command, args []string) error
+
+	// rebuild constructs a fresh copy of a command created by Command, without its sub-commands.
+	// It is nil for commands that were set up via BindConfig.
+	rebuild func(env envSource, stdout, stderr io.Writer) (*cobra.Command, []*EnvError, error)
+}
+
+// bindingFlag is the name of the hidden flag that holds the *binding of a command as its value, so
+// that nicecmd's knowledge of a command is dropped along with it.
+const bindingFlag = "nicecmd-binding"
+
+func (b *binding) String() string   { return "" }
+func (b *binding) Set(string) error { return errors.New("reserved by nicecmd") }
+func (b *binding) Type() string     { return "binding" }
+
+// storeBinding attaches b to cmd, replacing a binding that cmd already has.
+func storeBinding(cmd *cobra.Command, b *binding) error {
+	if f := cmd.Flags().Lookup(bindingFlag); f != nil {
+		if !isBindingFlag(f) {
+			return &BindError{Msg: fmt.Sprintf("flag %q is reserved by nicecmd", bindingFlag)}
+		}
+		f.Value = b
+		return nil
+	}
+	cmd.Flags().VarPF(b, bindingFlag, "", "").Hidden = true
+	return nil
+}
+
+func lookupBinding(cmd *cobra.Command) *binding {
+	if f := cmd.Flags().Lookup(bindingFlag); f != nil {
+		if b, ok := f.Value.(*binding); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// isBindingFlag reports whether flag is the one holding a *binding, which is not part of the
+// command's configuration.
+func isBindingFlag(flag *pflag.Flag) bool {
+	_, ok := flag.Value.(*binding)
+	return ok
+}
+
+// cloneTree rebuilds cmd and its sub-commands with fresh configuration and flag state.
+func cloneTree(cmd *cobra.Command, env envSource, stdout, stderr io.Writer,
+) (*cobra.Command, []*EnvError, error) {
+	b := lookupBinding(cmd)
+	if b == nil || b.rebuild == nil {
+		return nil, nil, &BindError{Msg: fmt.Sprintf("command %q was not created by nicecmd.Command and cannot be cloned", cmd.CommandPath())}
+	}
+	clone, envErrs, err := b.rebuild(env, stdout, stderr)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, sub := range cmd.Commands() {
+		if isDefaultCommand(sub) {
+			continue // Cobra adds these again when the clone is executed
+		}
+		subClone, subEnvErrs, err := cloneTree(sub, env, stdout, stderr)
+		if err != nil {
+			return nil, nil, err
+		}
+		clone.AddCommand(subClone)
+		envErrs = append(envErrs, subEnvErrs...)
+	}
+	return clone, envErrs, nil
+}
+
+// isDefaultCommand reports whether cmd is one of the commands that Cobra adds on execution.
+func isDefaultCommand(cmd *cobra.Command) bool {
+	return lookupBinding(cmd) == nil && (cmd.Name() == "help" || cmd.Name() == "completion")
+}
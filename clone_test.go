@@ -0,0 +1,129 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestCloneTree(t *testing.T) {
+	root := Command("TEST", Run(trivialRun), cobra.Command{Use: "root", Args: cobra.ArbitraryArgs}, TrivialConf{Foo: "default"})
+	root.AddCommand(Command("TEST_SUB", Run(trivialRun), cobra.Command{Use: "sub"}, TrivialConf{}))
+	root.SetArgs([]string{"--foo", "foo"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+
+	clone, envErrs, err := cloneTree(root, mapEnv(map[string]string{"TEST_SUB_FOO": "foo"}), nil, nil)
+	if err != nil || len(envErrs) != 0 {
+		t.Fatalf("clone: %v, %v", err, envErrs)
+	}
+	if clone == root {
+		t.Fatal("expected a new command")
+	}
+	if got := lookupBinding(clone).cfg.(*TrivialConf).Foo; got != "default" {
+		t.Errorf("expected clone to start with defaults, got %q", got)
+	}
+	if names := commandNames(clone); len(names) != 1 || names[0] != "sub" {
+		t.Errorf("expected only sub-command sub, got %v", names)
+	}
+	if got := lookupBinding(clone.Commands()[0]).cfg.(*TrivialConf).Foo; got != "foo" {
+		t.Errorf("expected environment of clone to be applied, got %q", got)
+	}
+	if got := lookupBinding(root).cfg.(*TrivialConf).Foo; got != "foo" {
+		t.Errorf("expected original to be unaffected, got %q", got)
+	}
+}
+
+func TestCloneTree_NotNiceCmd(t *testing.T) {
+	root := Command("TEST", Run(trivialRun), cobra.Command{Use: "root"}, TrivialConf{})
+	root.AddCommand(&cobra.Command{Use: "plain"})
+	if _, _, err := cloneTree(root, osEnv, nil, nil); err == nil {
+		t.Error("expected error for plain Cobra command")
+	}
+}
+
+func commandNames(cmd *cobra.Command) (names []string) {
+	for _, sub := range cmd.Commands() {
+		names = append(names, sub.Name())
+	}
+	return
+}
+
+func TestClone(t *testing.T) {
+	root := Command("TEST", Run(trivialRun), cobra.Command{Use: "root"}, TrivialConf{Foo: "default"})
+	t.Setenv("TEST_FOO", "env")
+	clone, err := Clone(root)
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if got := lookupBinding(clone).cfg.(*TrivialConf).Foo; got != "env" {
+		t.Errorf("expected process environment to be applied, got %q", got)
+	}
+	if got := lookupBinding(root).cfg.(*TrivialConf).Foo; got != "default" {
+		t.Errorf("expected original to be unaffected, got %q", got)
+	}
+	if _, err := Clone(&cobra.Command{Use: "plain"}); err == nil {
+		t.Error("expected error for plain Cobra command")
+	}
+}
+
+func TestLookupBinding(t *testing.T) {
+	cmd := Command("TEST", Run(trivialRun), cobra.Command{Use: "test"}, TrivialConf{Foo: "default"})
+	if b := lookupBinding(cmd); b == nil || b.cfg.(*TrivialConf).Foo != "default" {
+		t.Fatalf("expected the binding to be kept on the command, got %+v", b)
+	}
+	if flag := cmd.Flags().Lookup(bindingFlag); flag == nil || !flag.Hidden {
+		t.Errorf("expected a hidden flag holding the binding, got %+v", flag)
+	}
+	if lookupBinding(&cobra.Command{Use: "plain"}) != nil {
+		t.Error("expected no binding for plain Cobra command")
+	}
+	if _, ok := Flatten(TrivialConf{})[bindingFlag]; ok {
+		t.Error("expected Flatten to omit the binding")
+	}
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := BindFlagSet("TEST", fs, &TrivialConf{}); err != nil || fs.Lookup(bindingFlag) != nil {
+		t.Errorf("expected BindFlagSet to omit the binding, got %v", err)
+	}
+	cmd.SetArgs([]string{"--" + bindingFlag, "x"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	if err := cmd.Execute(); err == nil {
+		t.Error("expected the binding to reject values from the command line")
+	}
+}
+
+func TestClone_Collected(t *testing.T) {
+	root := Command("TEST", Run(trivialRun), cobra.Command{Use: "root"}, TrivialConf{})
+	root.AddCommand(Command("TEST_SUB", Run(trivialRun), cobra.Command{Use: "sub"}, TrivialConf{}))
+	collected := make(chan struct{}, 1)
+	func() {
+		clone, err := Clone(root)
+		if err != nil {
+			t.Fatalf("clone: %v", err)
+		}
+		sub := clone.Commands()[0]
+		clone.SetArgs([]string{"sub", "--foo", "foo"})
+		clone.SetOut(io.Discard)
+		clone.SetErr(io.Discard)
+		if err := clone.Execute(); err != nil {
+			t.Fatalf("execute: %v", err)
+		}
+		runtime.SetFinalizer(lookupBinding(sub).cfg.(*TrivialConf), func(*TrivialConf) {
+			collected <- struct{}{}
+		})
+	}()
+	for i := 0; i < 10; i++ {
+		runtime.GC()
+		select {
+		case <-collected:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Error("expected a discarded copy to be garbage collected")
+}
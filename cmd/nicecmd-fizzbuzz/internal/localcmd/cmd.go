@@ -11,16 +11,19 @@ import (
 )
 
 type Config struct {
-	Limit int `usage:"stop fizzbuzzing at this number"`
+	Limit int    `usage:"stop fizzbuzzing at this number"`
+	Fizz  string `arg:"0"`
+	Buzz  string `arg:"1"`
 }
 
 func NewCommand() *cobra.Command {
 	return nicecmd.Command("FIZZLOCAL", nicecmd.Run(run), cobra.Command{
 		Use:   "local [--limit <num>] [fizz text] [buzz text]",
 		Short: "fizz and buzz on the local console",
-		Args:  cobra.MaximumNArgs(2),
 	}, Config{
 		Limit: 100,
+		Fizz:  "Fizz",
+		Buzz:  "Buzz",
 	})
 }
 
@@ -29,8 +32,7 @@ func run(cfg Config, cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("limit must be >0, but got %d", cfg.Limit)
 	}
 
-	text := append(args, "Fizz", "Buzz")
-	fb := &FizzBuzzer{Fizz: text[0], Buzz: text[1]}
+	fb := &FizzBuzzer{Fizz: cfg.Fizz, Buzz: cfg.Buzz}
 
 	log := logutil.FromContext(cmd.Context())
 	log.Info("local fizzbuzzer starting", slog.Int("limit", cfg.Limit))
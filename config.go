@@ -0,0 +1,535 @@
+package nicecmd
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UnmarshalFunc decodes a configuration file into v, with the signature of json.Unmarshal.
+type UnmarshalFunc func(data []byte, v any) error
+
+// ConfigFormats maps file extensions to the decoders of configuration files. Only JSON is built
+// in, as nicecmd does not add dependencies. Others can be added as needed, e.g. yaml.Unmarshal of
+// gopkg.in/yaml.v3 for ".yaml" and ".yml", or toml.Unmarshal of github.com/BurntSushi/toml for
+// ".toml". Decoders are given a *map[string]any, and tables of TOML map to sub-structs like nested
+// objects of JSON.
+var ConfigFormats = map[string]UnmarshalFunc{
+	".json": json.Unmarshal,
+}
+
+// WithConfigFlag adds a persistent flag of the given name to the command, e.g. "config", which
+// takes the path or HTTPS URL of a configuration file, or "-" for stdin. Before any hooks run, the
+// file's values are applied to the flags of the executed command that were set neither on the
+// command line nor via environment variables, so that they take precedence over defaults only, see
+// WithPrecedence for letting them override environment variables. The flag can be repeated to
+// layer files, e.g. a base configuration and overrides for production, with later files taking
+// precedence.
+//
+// A document on stdin is read completely before the hooks run, and can thus not be combined with
+// commands that read stdin themselves. It is decoded as JSON, unless a decoder is registered for
+// the extension "-" in ConfigFormats.
+//
+// Keys are matched against flag names, and may also be given in camel or snake case. Keys of
+// nested objects are joined with dashes, so that nested objects map to sub-structs. Lists are
+// applied to slice flags, and objects to map flags. Keys without a flag are ignored.
+//
+// Sub-commands that are not created by nicecmd must not set their own persistent pre-run hooks,
+// as Cobra would then skip the hook that applies the file.
+func WithConfigFlag(name string) Option {
+	return func(o *options) {
+		o.config.flag = name
+	}
+}
+
+// WithStrictConfig makes configuration files fail with ErrUnknownKey if they
+// contain keys that match no flag of the command or its sub-commands, e.g. because of a typo.
+func WithStrictConfig() Option {
+	return func(o *options) {
+		o.config.strict = true
+	}
+}
+
+// WithConfigDiscovery makes the command look for configuration files of the application app in
+// the usual places, and apply them like files given via WithConfigFlag, which take precedence.
+// From lowest to highest precedence, these are:
+//
+//   - /etc/<app>/config.<ext>, except on Windows
+//   - <user config dir>/<app>/config.<ext>, i.e. $XDG_CONFIG_HOME or ~/.config on Linux, see
+//     os.UserConfigDir
+//   - <app>.<ext> in the working directory
+//
+// The extensions are those of ConfigFormats, in alphabetical order. Files that do not exist are
+// skipped.
+func WithConfigDiscovery(app string) Option {
+	return func(o *options) {
+		o.config.app = app
+	}
+}
+
+// ConfigSource provides configuration values from somewhere other than a file, e.g. from a secret
+// store. See package github.com/mologie/nicecmd/vault for an example.
+type ConfigSource interface {
+	// Name identifies the source in a *ConfigError, e.g. by its URL.
+	Name() string
+
+	// Load returns the values of the source like a decoded configuration file, i.e. as nested
+	// objects whose keys match flag names. It is called before the hooks of each execution.
+	Load(ctx context.Context) (map[string]any, error)
+}
+
+// WithConfigSource applies the values of src like those of a configuration file. Sources take
+// precedence over configuration files, with later sources taking precedence over earlier ones,
+// but not over flags set on the command line or via environment variables.
+func WithConfigSource(src ConfigSource) Option {
+	return func(o *options) {
+		o.config.sources = append(o.config.sources, src)
+	}
+}
+
+// Source is a source of flag values, see WithPrecedence.
+type Source int
+
+const (
+	SourceFlag    Source = iota + 1 // the command line
+	SourceEnv                       // environment variables, including environment files
+	SourceConfig                    // configuration files and sources
+	SourceDefault                   // defaults of the configuration struct and of tags
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceFlag:
+		return "flag"
+	case SourceEnv:
+		return "env"
+	case SourceConfig:
+		return "config"
+	case SourceDefault:
+		return "default"
+	}
+	return fmt.Sprintf("Source(%d)", int(s))
+}
+
+// defaultPrecedence is the order of sources unless WithPrecedence is given.
+var defaultPrecedence = []Source{SourceFlag, SourceEnv, SourceConfig, SourceDefault}
+
+// WithPrecedence changes which sources of flag values take precedence, from highest to lowest. The
+// default is SourceFlag, SourceEnv, SourceConfig, SourceDefault. As the command line always wins
+// and defaults never do, the only other supported order is SourceFlag, SourceConfig, SourceEnv,
+// SourceDefault, for deployments where configuration files are managed centrally and must
+// override the environment. Other orders fail with a *BindError.
+//
+// Like options that concern configuration files, pass it to the commands that read them.
+func WithPrecedence(order ...Source) Option {
+	return func(o *options) {
+		o.config.precedence = order
+	}
+}
+
+// ErrUnknownKey is wrapped by a *ConfigError for keys that WithStrictConfig rejects.
+var ErrUnknownKey = errors.New("unknown key")
+
+// configOptions are the options of a command's configuration files.
+type configOptions struct {
+	flag    string // name of the flag added via WithConfigFlag, empty if none
+	app     string // name of the application for WithConfigDiscovery, empty if none
+	strict  bool
+	remote  remoteConfig
+	sources []ConfigSource
+
+	precedence []Source // of WithPrecedence, nil for defaultPrecedence
+}
+
+func (c configOptions) enabled() bool {
+	return c.flag != "" || c.app != "" || len(c.sources) != 0
+}
+
+// checkPrecedence verifies that the order of WithPrecedence is supported.
+func (c configOptions) checkPrecedence() error {
+	if c.precedence == nil || slices.Equal(c.precedence, defaultPrecedence) {
+		return nil
+	}
+	if !slices.Equal(c.precedence, []Source{SourceFlag, SourceConfig, SourceEnv, SourceDefault}) {
+		return &BindError{Msg: fmt.Sprintf("unsupported precedence %v", c.precedence)}
+	}
+	return nil
+}
+
+// overEnv reports whether configuration files take precedence over environment variables.
+func (c configOptions) overEnv() bool {
+	return slices.Index(c.precedence, SourceConfig) < slices.Index(c.precedence, SourceEnv)
+}
+
+// configPaths returns the candidate paths of WithConfigDiscovery for app, with the lowest
+// precedence first. It is a variable for tests.
+var configPaths = func(app string) (paths []string) {
+	var dirs []string
+	if runtime.GOOS != "windows" {
+		dirs = append(dirs, filepath.Join("/etc", app))
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, app))
+	}
+	for _, dir := range dirs {
+		for _, ext := range configExtensions() {
+			paths = append(paths, filepath.Join(dir, "config."+ext))
+		}
+	}
+	for _, ext := range configExtensions() {
+		paths = append(paths, app+"."+ext)
+	}
+	return paths
+}
+
+// ConfigError reports a configuration file that cannot be read, or a value in it that cannot be
+// applied to its flag. Like for EnvError, values of secret flags are not retained.
+type ConfigError struct {
+	Path   string // of the file, or the name of the ConfigSource
+	Key    string // path of the key within the file, e.g. "log.level", empty for the file itself
+	Value  string
+	Err    error
+	Secret bool
+}
+
+func (e *ConfigError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf(DefaultMessages.InvalidConfigFile, e.Path, e.Err)
+	} else if e.Secret {
+		return fmt.Sprintf(DefaultMessages.InvalidSecretConfig, e.Path, e.Key)
+	}
+	return fmt.Sprintf(DefaultMessages.InvalidConfig, e.Path, e.Key, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// setupConfig adds the flag of WithConfigFlag to cmd, if any. The configuration files are applied
+// by the persistent pre-run hook of cmd, see chainPersistentPreRun.
+func setupConfig(cmd *cobra.Command, config configOptions) error {
+	if name := config.flag; name != "" {
+		if cmd.PersistentFlags().Lookup(name) != nil || cmd.Flags().Lookup(name) != nil {
+			return &BindError{Msg: fmt.Sprintf("config flag %q is already defined", name)}
+		}
+		cmd.PersistentFlags().StringArray(name, nil, DefaultMessages.ConfigUsage)
+		_ = cmd.MarkPersistentFlagFilename(name, configExtensions()...)
+	}
+	lookupBinding(cmd).config = config
+	if config.overEnv() {
+		// Let files override environment variables by not counting them as set until files are
+		// applied. Flags on the command line still mark themselves as changed when parsed.
+		cmd.Flags().VisitAll(func(f *pflag.Flag) {
+			if len(f.Annotations[annotationEnvName]) != 0 {
+				f.Changed = false
+			}
+		})
+	}
+
+	return nil
+}
+
+// configExtensions returns the extensions of ConfigFormats for shell completion.
+func configExtensions() []string {
+	exts := make([]string, 0, len(ConfigFormats))
+	for ext := range ConfigFormats {
+		if strings.HasPrefix(ext, ".") { // not the format of stdin
+			exts = append(exts, ext[1:])
+		}
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// applyConfigFlags applies the configuration files of cmd and all of its parents.
+func applyConfigFlags(cmd *cobra.Command) error {
+	for owner := cmd; owner != nil; owner = owner.Parent() {
+		if err := applyConfigFiles(owner, cmd); err != nil {
+			return err
+		}
+	}
+	markEnvChanged(cmd)
+	return nil
+}
+
+// markEnvChanged marks the flags of cmd that environment variables were applied to as changed,
+// which WithPrecedence defers until configuration files are applied.
+func markEnvChanged(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if len(f.Annotations[annotationEnvName]) != 0 && !f.Changed {
+			f.Changed = true
+		}
+	})
+}
+
+// applyConfigFiles applies the configuration files and sources of owner, if any, to the flags of
+// the executed command cmd.
+func applyConfigFiles(owner, cmd *cobra.Command) error {
+	b := lookupBinding(owner)
+	if b == nil || !b.config.enabled() {
+		return nil
+	}
+	var sources []ConfigSource
+	if b.config.app != "" {
+		for _, path := range configPaths(b.config.app) {
+			if _, err := b.files.stat(path); err == nil {
+				sources = append(sources, fileSource{path: path, files: b.files})
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return &ConfigError{Path: path, Err: err}
+			}
+		}
+	}
+	if b.config.flag != "" {
+		paths, _ := owner.PersistentFlags().GetStringArray(b.config.flag)
+		if i := slices.Index(paths, "-"); i != -1 && slices.Contains(paths[i+1:], "-") {
+			return &ConfigError{Path: "-", Err: errors.New("standard input can only be read once")}
+		}
+		for _, path := range paths {
+			sources = append(sources, fileSource{path: path, remote: b.config.remote, stdin: cmd.InOrStdin(), files: b.files})
+		}
+	}
+	sources = append(sources, b.config.sources...)
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background() // Resolve does not set a context
+	}
+	var known map[string]bool
+	if b.config.strict && len(sources) != 0 {
+		known = treeFlags(owner, make(map[string]bool))
+	}
+	// Later sources take precedence. Apply them first, so that they mark their flags as changed.
+	for i := len(sources) - 1; i >= 0; i-- {
+		l := &configLoader{name: sources[i].Name(), fs: cmd.Flags(), known: known, expander: b.expander}
+		doc, err := sources[i].Load(ctx)
+		if err != nil {
+			return &ConfigError{Path: l.name, Err: err}
+		}
+		if err := l.apply("", "", doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configLoader applies the document of a single configuration file or source.
+type configLoader struct {
+	name     string // of the file or source
+	fs       *pflag.FlagSet
+	known    map[string]bool // names of all flags of the tree for WithStrictConfig, nil if not strict
+	expander *expander       // nil unless WithExpansion is given
+}
+
+// treeFlags adds the names of all flags of cmd and its sub-commands to known, including flags
+// inherited from parents of cmd.
+func treeFlags(cmd *cobra.Command, known map[string]bool) map[string]bool {
+	add := func(f *pflag.Flag) {
+		if !isBindingFlag(f) {
+			known[f.Name] = true
+		}
+	}
+	cmd.InheritedFlags().VisitAll(add)
+	cmd.LocalFlags().VisitAll(add)
+	for _, sub := range cmd.Commands() {
+		treeFlags(sub, known)
+	}
+	return known
+}
+
+// fileSource is a configuration file given by its path or HTTPS URL, or "-" for stdin.
+type fileSource struct {
+	path   string
+	remote remoteConfig
+	stdin  io.Reader
+	files  fileSystem
+}
+
+// Name returns the path of the file, or its URL without credentials.
+func (f fileSource) Name() string {
+	if isURL(f.path) {
+		return redactURL(f.path)
+	}
+	return f.path
+}
+
+func (f fileSource) Load(ctx context.Context) (map[string]any, error) {
+	var data []byte
+	var ext string
+	var err error
+	if f.path == "-" {
+		ext = "-"
+		if _, ok := ConfigFormats[ext]; !ok {
+			ext = ".json"
+		}
+		data, err = io.ReadAll(f.stdin)
+	} else if isURL(f.path) {
+		data, ext, err = f.remote.fetch(ctx, f.path)
+	} else {
+		ext = filepath.Ext(f.path)
+		data, err = f.files.readFile(f.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	unmarshal, ok := ConfigFormats[strings.ToLower(ext)]
+	if !ok {
+		return nil, fmt.Errorf("unsupported format %q", ext)
+	}
+	var doc map[string]any
+	if err := unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// apply applies the values of doc, with keyPrefix being the path of doc within the file, and
+// flagPrefix the corresponding prefix of flag names.
+func (l *configLoader) apply(keyPrefix, flagPrefix string, doc map[string]any) error {
+	keys := make([]string, 0, len(doc))
+	for key := range doc {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value, keyPath, name := doc[key], keyPrefix+key, flagPrefix+configKey(key)
+		param := l.fs.Lookup(name)
+		if param != nil && isBindingFlag(param) {
+			param = nil
+		}
+		if m, ok := configObject(value); ok && (param == nil || !isMapFlag(param)) {
+			if err := l.apply(keyPath+".", name+"-", m); err != nil {
+				return err
+			}
+			continue
+		}
+		if l.known != nil && !l.known[name] {
+			return &ConfigError{Path: l.name, Key: keyPath, Err: ErrUnknownKey}
+		}
+		if param == nil || param.Changed || value == nil {
+			continue // flags and environment variables take precedence
+		}
+		text, err := l.set(param, value)
+		if err != nil {
+			if isSecret(param) {
+				return &ConfigError{Path: l.name, Key: keyPath, Secret: true}
+			}
+			return &ConfigError{Path: l.name, Key: keyPath, Value: text, Err: err}
+		}
+		param.Changed = true
+	}
+	return nil
+}
+
+// set sets param to the decoded value and returns the value as text for errors. The value replaces
+// that of environment variables under WithPrecedence, instead of appending to them for slices.
+func (l *configLoader) set(param *pflag.Flag, value any) (string, error) {
+	text := formatConfigValue(value)
+	slice, isSlice := param.Value.(pflag.SliceValue)
+	if list, ok := value.([]any); ok && isSlice {
+		// Set each element on its own, as not all slice flags parse their values as CSV
+		elems := make([]string, len(list))
+		for i, elem := range list {
+			elems[i] = formatConfigValue(elem)
+			if l.expander != nil {
+				var err error
+				if elems[i], err = l.expander.expand(elems[i]); err != nil {
+					return text, err
+				}
+			}
+		}
+		return text, slice.Replace(elems)
+	}
+	var err error
+	if l.expander != nil {
+		text, err = l.expander.expand(text)
+	}
+	if err == nil && isSlice {
+		err = slice.Replace(nil)
+	}
+	if err == nil {
+		err = param.Value.Set(text)
+	}
+	return text, err
+}
+
+// configKey converts a key of a configuration file to the corresponding part of a flag name. Words
+// separated by underscores or dashes are converted on their own, so that e.g. LOG_LEVEL, log_level
+// and logLevel all match log-level.
+func configKey(key string) string {
+	words := strings.FieldsFunc(key, func(r rune) bool { return r == '_' || r == '-' })
+	for i, word := range words {
+		words[i] = Slug(word, '-')
+	}
+	return strings.Join(words, "-")
+}
+
+// configObject returns value as an object, also accepting the map type of YAML v2 decoders.
+func configObject(value any) (map[string]any, bool) {
+	switch m := value.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		converted := make(map[string]any, len(m))
+		for k, v := range m {
+			converted[fmt.Sprint(k)] = v
+		}
+		return converted, true
+	}
+	return nil, false
+}
+
+func isMapFlag(param *pflag.Flag) bool {
+	return strings.HasPrefix(param.Value.Type(), "stringTo")
+}
+
+// formatConfigValue converts a decoded value to the string representation of flags.
+func formatConfigValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		// TOML has timestamps, which decoders return as time.Time
+		return v.Format(time.RFC3339Nano)
+	case []any:
+		// Slice flags parse their values as CSV, which also allows for commas in strings
+		record := make([]string, len(v))
+		for i, elem := range v {
+			record[i] = formatConfigValue(elem)
+		}
+		return formatCSV(record)
+	}
+	if m, ok := configObject(value); ok {
+		// Map flags parse their values as CSV as well, except for stringToInt and stringToInt64,
+		// whose values cannot contain commas
+		pairs := make([]string, 0, len(m))
+		for k, elem := range m {
+			pairs = append(pairs, k+"="+formatConfigValue(elem))
+		}
+		sort.Strings(pairs)
+		return formatCSV(pairs)
+	}
+	return fmt.Sprint(value)
+}
+
+func formatCSV(record []string) string {
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write(record)
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
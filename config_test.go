@@ -0,0 +1,467 @@
+package nicecmd
+
+import (
+	"context"
+	"errors"
+	"github.com/spf13/cobra"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+type configConf struct {
+	Port   int
+	Name   string
+	Tags   []string
+	Labels map[string]string
+	Log    struct{ Level string }
+	Token  string `flag:"secret"`
+	User   string `flag:"required"`
+}
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func executeConfig(t *testing.T, args ...string) (cfg configConf, err error) {
+	t.Helper()
+	cmd := Command("TEST", Run(func(c configConf, cmd *cobra.Command, args []string) error {
+		cfg = c
+		return nil
+	}), cobra.Command{Use: "test"}, configConf{Port: 80, Name: "default"}, WithConfigFlag("config"))
+	cmd.SetArgs(args)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	return cfg, cmd.Execute()
+}
+
+func TestWithConfigFlag(t *testing.T) {
+	path := writeConfig(t, "app.json", `{
+		"port": 8080,
+		"name": "file",
+		"tags": ["a", "b,c"],
+		"labels": {"x": "1", "y": 2},
+		"log": {"level": "debug"},
+		"user": "bob",
+		"unknown": true
+	}`)
+	t.Setenv("TEST_NAME", "env")
+
+	cfg, err := executeConfig(t, "--config", path, "--port", "9090")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := configConf{
+		Port:   9090,
+		Name:   "env",
+		Tags:   []string{"a", "b,c"},
+		Labels: map[string]string{"x": "1", "y": "2"},
+		User:   "bob",
+	}
+	want.Log.Level = "debug"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected flags > env > file > defaults, got %+v", cfg)
+	}
+}
+
+func TestWithConfigFlag_Lists(t *testing.T) {
+	type Conf struct {
+		Raw    []string `encoding:"raw" env:"-"`
+		Ports  []int
+		Labels map[string]string
+		Counts map[string]int
+	}
+	var got Conf
+	cmd := Command("TEST", Run(func(cfg Conf, cmd *cobra.Command, args []string) error {
+		got = cfg
+		return nil
+	}), cobra.Command{Use: "test"}, Conf{}, WithConfigFlag("config"))
+	cmd.SetArgs([]string{"--config", writeConfig(t, "app.json", `{
+		"raw": ["a,b", "c", "\"q\""],
+		"ports": [80, 443],
+		"labels": {"x": "1,2", "y": "a=b"},
+		"counts": {"a": 1, "b": 2}
+	}`)})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := Conf{
+		Raw:    []string{"a,b", "c", `"q"`},
+		Ports:  []int{80, 443},
+		Labels: map[string]string{"x": "1,2", "y": "a=b"},
+		Counts: map[string]int{"a": 1, "b": 2},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected elements to be kept as they are:\nwant %+v\ngot  %+v", want, got)
+	}
+}
+
+func TestWithConfigFlag_Unset(t *testing.T) {
+	cfg, err := executeConfig(t, "--user", "bob")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if cfg.Port != 80 {
+		t.Errorf("expected default without config file, got %d", cfg.Port)
+	}
+}
+
+func TestWithConfigFlag_KeyCase(t *testing.T) {
+	cmd := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{}, WithConfigFlag("config"))
+	for _, key := range []string{"logLevel", "log_level", "LogLevel", "LOG_LEVEL", "Log_Level", "log-level"} {
+		if got := configKey(key); got != "log-level" {
+			t.Errorf("expected key %q to match log-level, got %q", key, got)
+		}
+	}
+	if got := configKey("DB_HOST"); got != "db-host" {
+		t.Errorf("expected key DB_HOST to match db-host, got %q", got)
+	}
+	if cmd.PersistentFlags().Lookup("config") == nil {
+		t.Error("expected persistent config flag")
+	}
+}
+
+func TestWithConfigFlag_Errors(t *testing.T) {
+	var cfgErr *ConfigError
+	_, err := executeConfig(t, "--config", filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.As(err, &cfgErr) || cfgErr.Key != "" || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected *ConfigError for missing file, got %v", err)
+	}
+
+	_, err = executeConfig(t, "--config", writeConfig(t, "app.ini", ""))
+	if !errors.As(err, &cfgErr) || !strings.Contains(err.Error(), `unsupported format ".ini"`) {
+		t.Errorf("expected *ConfigError for unsupported format, got %v", err)
+	}
+
+	_, err = executeConfig(t, "--config", writeConfig(t, "app.json", `{"port": "x"}`))
+	if !errors.As(err, &cfgErr) || cfgErr.Key != "port" || cfgErr.Value != "x" {
+		t.Errorf("expected *ConfigError for invalid value, got %v", err)
+	}
+
+	cmd := Command("TEST", Run(func(c struct {
+		Token int `flag:"secret"`
+	}, cmd *cobra.Command, args []string) error {
+		return nil
+	}), cobra.Command{Use: "test"}, struct {
+		Token int `flag:"secret"`
+	}{}, WithConfigFlag("config"))
+	cmd.SetArgs([]string{"--config", writeConfig(t, "app.json", `{"token": "hunter2"}`)})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	err = cmd.Execute()
+	if !errors.As(err, &cfgErr) || !cfgErr.Secret || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("expected redacted *ConfigError for secret, got %v", err)
+	}
+}
+
+func TestWithConfigFlag_Sub(t *testing.T) {
+	type SubConf struct{ Level string }
+	var got SubConf
+	root := Command("TEST", RunFuncs[TrivialConf]{}, cobra.Command{Use: "root"}, TrivialConf{}, WithConfigFlag("config"))
+	root.AddCommand(Command("TEST_SUB", Run(func(cfg SubConf, cmd *cobra.Command, args []string) error {
+		got = cfg
+		return nil
+	}), cobra.Command{Use: "sub"}, SubConf{}))
+	root.SetArgs([]string{"sub", "--config", writeConfig(t, "app.json", `{"level": "info"}`)})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got.Level != "info" {
+		t.Errorf("expected config file to apply to sub-command, got %q", got.Level)
+	}
+}
+
+func TestWithConfigFlag_SubParentHook(t *testing.T) {
+	type RootConf struct {
+		Level string `flag:"persistent"`
+	}
+	type SubConf struct{ Name string }
+	var calls []string
+	var got SubConf
+	root := Command("TEST", PersistentPreRun(func(cfg RootConf, cmd *cobra.Command, args []string) error {
+		calls = append(calls, "root.pre "+cfg.Level)
+		return nil
+	}), cobra.Command{Use: "root"}, RootConf{}, WithConfigFlag("config"))
+	root.AddCommand(Command("TEST_SUB", Run(func(cfg SubConf, cmd *cobra.Command, args []string) error {
+		got = cfg
+		return nil
+	}), cobra.Command{Use: "sub"}, SubConf{}, WithConfigFlag("sub-config"), WithConfigSource(mapSource{"name": "source"})))
+	root.SetArgs([]string{"sub", "--config", writeConfig(t, "root.json", `{"level": "debug"}`)})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !slices.Equal(calls, []string{"root.pre debug"}) {
+		t.Errorf("expected the root's hook to run with its config file applied, got %q", calls)
+	}
+	if got.Name != "source" {
+		t.Errorf("expected the sub-command's config source to apply, got %q", got.Name)
+	}
+}
+
+func TestWithConfigFlag_Resolve(t *testing.T) {
+	root := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{}, WithConfigFlag("config"))
+	cfg, err := Resolve[configConf](root, []string{"--config", writeConfig(t, "app.json", `{"user": "bob"}`)}, map[string]string{})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.User != "bob" {
+		t.Errorf("expected Resolve to apply the config file, got %q", cfg.User)
+	}
+}
+
+func TestConfigFormats(t *testing.T) {
+	defer func() { delete(ConfigFormats, ".kv") }()
+	ConfigFormats[".kv"] = func(data []byte, v any) error {
+		// decodes like YAML v2, with map[any]any for nested objects
+		*v.(*map[string]any) = map[string]any{"log": map[any]any{"level": string(data)}, "user": "bob"}
+		return nil
+	}
+	cfg, err := executeConfig(t, "--config", writeConfig(t, "app.KV", "warn"))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("expected custom format to be used, got %q", cfg.Log.Level)
+	}
+}
+
+func TestConfigFormats_TOML(t *testing.T) {
+	defer func() { delete(ConfigFormats, ".toml") }()
+	ConfigFormats[".toml"] = func(data []byte, v any) error {
+		// decodes like TOML libraries, with int64 and time.Time values
+		*v.(*map[string]any) = map[string]any{
+			"port": int64(8080),
+			"user": time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			"log":  map[string]any{"level": "info"},
+		}
+		return nil
+	}
+	cfg, err := executeConfig(t, "--config", writeConfig(t, "app.toml", ""))
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if cfg.Port != 8080 || cfg.User != "2024-01-02T03:04:05Z" || cfg.Log.Level != "info" {
+		t.Errorf("expected TOML values to be applied, got %+v", cfg)
+	}
+}
+
+func TestWithStrictConfig(t *testing.T) {
+	type SubConf struct{ Level string }
+	newRoot := func() *cobra.Command {
+		root := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "root"}, configConf{},
+			WithConfigFlag("config"), WithStrictConfig())
+		root.AddCommand(Command("TEST_SUB", Run(func(cfg SubConf, cmd *cobra.Command, args []string) error {
+			return nil
+		}), cobra.Command{Use: "sub"}, SubConf{}))
+		root.SetOut(io.Discard)
+		root.SetErr(io.Discard)
+		return root
+	}
+
+	root := newRoot()
+	root.SetArgs([]string{"sub", "--config", writeConfig(t, "app.json", `{"port": 1, "level": "info"}`)})
+	if err := root.Execute(); err != nil {
+		t.Errorf("expected keys of sub-commands to be accepted, got %v", err)
+	}
+
+	for _, doc := range []string{`{"prot": 1}`, `{"log": {"lvl": "info"}}`, `{"nicecmd-binding": 1}`} {
+		root := newRoot()
+		root.SetArgs([]string{"sub", "--config", writeConfig(t, "app.json", doc)})
+		var cfgErr *ConfigError
+		if err := root.Execute(); !errors.As(err, &cfgErr) || !errors.Is(err, ErrUnknownKey) {
+			t.Errorf("expected ErrUnknownKey for %s, got %v", doc, err)
+		}
+	}
+}
+
+func TestWithConfigFlag_Layered(t *testing.T) {
+	base := writeConfig(t, "base.json", `{"port": 8080, "user": "bob", "log": {"level": "info"}}`)
+	prod := writeConfig(t, "prod.json", `{"port": 443, "log": {"level": "warn"}}`)
+	cfg, err := executeConfig(t, "--config", base, "--config", prod)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if cfg.Port != 443 || cfg.User != "bob" || cfg.Log.Level != "warn" {
+		t.Errorf("expected later files to take precedence, got %+v", cfg)
+	}
+}
+
+func TestWithPrecedence(t *testing.T) {
+	base := writeConfig(t, "base.json", `{"port": 8080, "name": "base", "tags": ["x", "y"], "log": {"level": "info"}}`)
+	prod := writeConfig(t, "prod.json", `{"port": 443, "name": "prod"}`)
+	environ := WithEnviron([]string{"TEST_PORT=1", "TEST_NAME=env", "TEST_TAGS=a,b", "TEST_LOG_LEVEL=debug", "TEST_USER=bob"})
+
+	var cfg configConf
+	cmd := Command("TEST", Run(func(c configConf, cmd *cobra.Command, args []string) error {
+		cfg = c
+		return nil
+	}), cobra.Command{Use: "test"}, configConf{}, environ, WithConfigFlag("config"),
+		WithPrecedence(SourceFlag, SourceConfig, SourceEnv, SourceDefault))
+	cmd.SetArgs([]string{"--config", base, "--config", prod, "--name", "flag"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := configConf{Port: 443, Name: "flag", Tags: []string{"x", "y"}, User: "bob"}
+	want.Log.Level = "info"
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected flags > file > env > defaults, got %+v", cfg)
+	}
+
+	var bindErr *BindError
+	_, err := TryCommand("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{},
+		WithPrecedence(SourceEnv, SourceFlag, SourceConfig, SourceDefault))
+	if !errors.As(err, &bindErr) || !strings.Contains(err.Error(), "[env flag config default]") {
+		t.Errorf("expected *BindError for unsupported precedence, got %v", err)
+	}
+}
+
+func TestWithConfigDiscovery(t *testing.T) {
+	system := writeConfig(t, "system.json", `{"port": 1, "user": "bob", "name": "system"}`)
+	local := writeConfig(t, "local.json", `{"port": 2}`)
+	defer func(f func(string) []string) { configPaths = f }(configPaths)
+	configPaths = func(app string) []string {
+		if app != "app" {
+			t.Errorf("expected app name, got %q", app)
+		}
+		return []string{system, filepath.Join(t.TempDir(), "missing.json"), local}
+	}
+
+	cmd := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{},
+		WithConfigDiscovery("app"), WithConfigFlag("config"))
+	cfg, err := Resolve[configConf](cmd, []string{"--config", writeConfig(t, "flag.json", `{"name": "flag"}`)}, map[string]string{})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Port != 2 || cfg.User != "bob" || cfg.Name != "flag" {
+		t.Errorf("expected discovered files in order, then the flag's, got %+v", cfg)
+	}
+}
+
+func TestConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir) // for platforms that ignore XDG_CONFIG_HOME
+	paths := configPaths("app")
+	userDir, err := os.UserConfigDir()
+	if err != nil {
+		t.Fatalf("user config dir: %v", err)
+	}
+	userIndex := slices.Index(paths, filepath.Join(userDir, "app", "config.json"))
+	localIndex := slices.Index(paths, "app.json")
+	if userIndex == -1 || localIndex < userIndex {
+		t.Errorf("expected user config before working directory, got %v", paths)
+	}
+	if runtime.GOOS != "windows" && slices.Index(paths, filepath.Join("/etc", "app", "config.json")) != 0 {
+		t.Errorf("expected /etc first, got %v", paths)
+	}
+}
+
+type mapSource map[string]any
+
+func (m mapSource) Name() string {
+	return "map"
+}
+
+func (m mapSource) Load(ctx context.Context) (map[string]any, error) {
+	if m == nil {
+		return nil, errors.New("unavailable")
+	}
+	return m, nil
+}
+
+func TestWithConfigSource(t *testing.T) {
+	cmd := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{},
+		WithConfigFlag("config"), WithConfigSource(mapSource{"port": 1, "name": "first"}),
+		WithConfigSource(mapSource{"port": 2}))
+	path := writeConfig(t, "app.json", `{"port": 3, "name": "file", "user": "bob"}`)
+	cfg, err := Resolve[configConf](cmd, []string{"--config", path}, map[string]string{})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Port != 2 || cfg.Name != "first" || cfg.User != "bob" {
+		t.Errorf("expected sources to take precedence over files, got %+v", cfg)
+	}
+
+	cmd = Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{},
+		WithConfigSource(mapSource(nil)))
+	var cfgErr *ConfigError
+	if _, err := Resolve[configConf](cmd, nil, map[string]string{}); !errors.As(err, &cfgErr) || cfgErr.Path != "map" {
+		t.Errorf("expected *ConfigError naming the source, got %v", err)
+	}
+}
+
+func TestWithConfigSource_EnvStyleKeys(t *testing.T) {
+	// Sources such as the Windows registry name their values like environment variables
+	type Conf struct {
+		Log struct{ Level string }
+		DB  struct{ Host string }
+	}
+	cmd := Command("TEST", RunFuncs[Conf]{}, cobra.Command{Use: "test"}, Conf{},
+		WithConfigSource(mapSource{"LOG_LEVEL": "debug", "DB_HOST": "db.example.com"}))
+	cfg, err := Resolve[Conf](cmd, nil, map[string]string{})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Log.Level != "debug" || cfg.DB.Host != "db.example.com" {
+		t.Errorf("expected upper-case keys to match flags, got %+v", cfg)
+	}
+}
+
+func TestWithConfigFlag_Stdin(t *testing.T) {
+	cmd := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{}, WithConfigFlag("config"))
+	var got configConf
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		got = *lookupBinding(cmd).cfg.(*configConf)
+		return nil
+	}
+	base := writeConfig(t, "base.json", `{"port": 1, "name": "base"}`)
+	cmd.SetArgs([]string{"--config", base, "--config", "-"})
+	cmd.SetIn(strings.NewReader(`{"port": 2, "user": "bob"}`))
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if got.Port != 2 || got.Name != "base" || got.User != "bob" {
+		t.Errorf("expected document from stdin, got %+v", got)
+	}
+
+	cmd.SetArgs([]string{"--config", "-", "--config", "-"})
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	var cfgErr *ConfigError
+	if err := cmd.Execute(); !errors.As(err, &cfgErr) {
+		t.Errorf("expected *ConfigError for reading stdin twice, got %v", err)
+	}
+}
+
+func TestWithConfigFlag_StdinFormat(t *testing.T) {
+	defer func() { delete(ConfigFormats, "-") }()
+	ConfigFormats["-"] = func(data []byte, v any) error {
+		*v.(*map[string]any) = map[string]any{"user": strings.TrimSpace(string(data))}
+		return nil
+	}
+	if slices.Contains(configExtensions(), "-") {
+		t.Error("expected format of stdin not to be offered for completion or discovery")
+	}
+	cmd := Command("TEST", Run(func(cfg configConf, cmd *cobra.Command, args []string) error {
+		if cfg.User != "bob" {
+			t.Errorf("expected registered format of stdin, got %q", cfg.User)
+		}
+		return nil
+	}), cobra.Command{Use: "test"}, configConf{}, WithConfigFlag("config"))
+	cmd.SetArgs([]string{"--config", "-"})
+	cmd.SetIn(strings.NewReader("bob\n"))
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+}
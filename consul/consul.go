@@ -0,0 +1,138 @@
+// Package consul reads configuration values from the KV store of HashiCorp Consul. It talks to
+// Consul's HTTP API directly, so that programs do not need to depend on Consul's client library.
+//
+// Use it as a source of a nicecmd command:
+//
+//	cmd := nicecmd.Command("APP_SERVE", run, cobra.Command{Use: "serve"}, Config{},
+//		nicecmd.WithConfigSource(&consul.Source{Prefix: "app/serve"}))
+//
+// Keys below the prefix are matched against flag names like those of configuration files, and
+// further path segments map to sub-structs. For example, app/serve/log/level sets --log-level. With
+// nicecmd.WithStrictConfig, keys that match no flag are rejected.
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Source reads all keys below a prefix of Consul's KV store.
+type Source struct {
+	// Address of the Consul agent, e.g. "http://127.0.0.1:8500". Defaults to $CONSUL_HTTP_ADDR,
+	// or to the local agent if that is not set either.
+	Address string
+
+	// Token to authenticate with. Defaults to $CONSUL_HTTP_TOKEN.
+	Token string
+
+	// Prefix of the keys, e.g. "app/serve".
+	Prefix string
+
+	// Client for requests to Consul. Defaults to a client with a timeout of 10 seconds.
+	Client *http.Client
+}
+
+// EnvPrefix returns the KV prefix that corresponds to the environment prefix of a command, e.g.
+// "myapp/sub" for "MYAPP_SUB", so that the KV store can mirror the environment variables.
+func EnvPrefix(envPrefix string) string {
+	return strings.ReplaceAll(strings.ToLower(envPrefix), "_", "/")
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Name returns the prefix of the keys, prefixed with "consul:".
+func (s *Source) Name() string {
+	return "consul:" + s.Prefix
+}
+
+// Load reads the keys below the prefix, and returns them as nested objects.
+func (s *Source) Load(ctx context.Context) (map[string]any, error) {
+	address, token := s.Address, s.Token
+	if address == "" {
+		address = os.Getenv("CONSUL_HTTP_ADDR")
+	}
+	if address == "" {
+		address = "http://127.0.0.1:8500"
+	} else if !strings.Contains(address, "://") {
+		address = "http://" + address // like Consul's CLI
+	}
+	if token == "" {
+		token = os.Getenv("CONSUL_HTTP_TOKEN")
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	prefix := strings.Trim(s.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	u := strings.TrimSuffix(address, "/") + "/v1/kv/" + (&url.URL{Path: prefix}).EscapedPath() + "?recurse=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil // no keys below the prefix
+	} else if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var pairs []struct {
+		Key   string
+		Value []byte // base64 in JSON
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&pairs); err != nil {
+		return nil, err
+	}
+	doc := make(map[string]any)
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, prefix)
+		if key == "" || strings.HasSuffix(key, "/") {
+			continue // folders
+		}
+		if err := insert(doc, strings.Split(key, "/"), string(pair.Value)); err != nil {
+			return nil, fmt.Errorf("key %s: %w", pair.Key, err)
+		}
+	}
+	return doc, nil
+}
+
+// insert sets the value at the path of nested objects in doc.
+func insert(doc map[string]any, path []string, value string) error {
+	for _, segment := range path[:len(path)-1] {
+		switch next := doc[segment].(type) {
+		case nil:
+			child := make(map[string]any)
+			doc[segment] = child
+			doc = child
+		case map[string]any:
+			doc = next
+		default:
+			return fmt.Errorf("%s is both a value and a folder", segment)
+		}
+	}
+	last := path[len(path)-1]
+	if _, ok := doc[last].(map[string]any); ok {
+		return fmt.Errorf("%s is both a value and a folder", last)
+	}
+	doc[last] = value
+	return nil
+}
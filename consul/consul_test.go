@@ -0,0 +1,103 @@
+package consul_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/mologie/nicecmd"
+	"github.com/mologie/nicecmd/consul"
+	"github.com/spf13/cobra"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type pair struct {
+	Key   string
+	Value []byte
+}
+
+func newConsul(t *testing.T, pairs ...pair) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "root" {
+			http.Error(w, "ACL not found", http.StatusForbidden)
+			return
+		}
+		prefix := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		if r.URL.Query().Get("recurse") != "true" {
+			t.Errorf("expected recursive request, got %s", r.URL)
+		}
+		var found []pair
+		for _, p := range pairs {
+			if strings.HasPrefix(p.Key, prefix) {
+				found = append(found, p)
+			}
+		}
+		if len(found) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(found)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSource_Load(t *testing.T) {
+	srv := newConsul(t,
+		pair{Key: "app/sub/"},
+		pair{Key: "app/sub/port", Value: []byte("8080")},
+		pair{Key: "app/sub/log/level", Value: []byte("debug")},
+		pair{Key: "other/port", Value: []byte("1")},
+	)
+	src := &consul.Source{Address: srv.URL, Token: "root", Prefix: consul.EnvPrefix("APP_SUB")}
+	doc, err := src.Load(context.Background())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if doc["port"] != "8080" || doc["log"].(map[string]any)["level"] != "debug" || len(doc) != 2 {
+		t.Errorf("unexpected document: %v", doc)
+	}
+
+	src.Prefix = "missing"
+	if doc, err := src.Load(context.Background()); err != nil || len(doc) != 0 {
+		t.Errorf("expected no keys for missing prefix, got %v, %v", doc, err)
+	}
+
+	src.Token = "wrong"
+	if _, err := src.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "ACL not found") {
+		t.Errorf("expected error of Consul, got %v", err)
+	}
+}
+
+func TestSource_Conflict(t *testing.T) {
+	srv := newConsul(t, pair{Key: "app/log", Value: []byte("x")}, pair{Key: "app/log/level", Value: []byte("y")})
+	src := &consul.Source{Address: srv.URL, Token: "root", Prefix: "app"}
+	if _, err := src.Load(context.Background()); err == nil {
+		t.Error("expected error for key that is both a value and a folder")
+	}
+}
+
+func TestSource_Command(t *testing.T) {
+	srv := newConsul(t, pair{Key: "app/port", Value: []byte("8080")}, pair{Key: "app/prot", Value: []byte("1")})
+	type Config struct {
+		Port int
+	}
+	cmd := nicecmd.Command("APP", nicecmd.RunFuncs[Config]{}, cobra.Command{Use: "app"}, Config{},
+		nicecmd.WithConfigSource(&consul.Source{Address: srv.URL, Token: "root", Prefix: "app"}))
+	cfg, err := nicecmd.Resolve[Config](cmd, nil, map[string]string{})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Port != 8080 {
+		t.Errorf("expected port from Consul, got %d", cfg.Port)
+	}
+
+	cmd = nicecmd.Command("APP", nicecmd.RunFuncs[Config]{}, cobra.Command{Use: "app"}, Config{},
+		nicecmd.WithConfigSource(&consul.Source{Address: srv.URL, Token: "root", Prefix: "app"}),
+		nicecmd.WithStrictConfig())
+	if _, err := nicecmd.Resolve[Config](cmd, nil, map[string]string{}); !errors.Is(err, nicecmd.ErrUnknownKey) {
+		t.Errorf("expected unknown key to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+	"maps"
+	"reflect"
+)
+
+// Interfaces implemented by configuration structs of commands declared via New.
+type (
+	persistentPreRunner interface {
+		PersistentPreRun(cmd *cobra.Command, args []string) error
+	}
+	preRunner interface {
+		PreRun(cmd *cobra.Command, args []string) error
+	}
+	runner interface {
+		Run(cmd *cobra.Command, args []string) error
+	}
+	postRunner interface {
+		PostRun(cmd *cobra.Command, args []string) error
+	}
+	persistentPostRunner interface {
+		PersistentPostRun(cmd *cobra.Command, args []string) error
+	}
+)
+
+// New creates a whole command tree from the configuration struct cli, which may also be a pointer
+// to one. Fields with a `cmd:"<use line>"` tag declare sub-commands, and their struct type is the
+// sub-command's configuration. The optional `short` tag sets the sub-command's short description.
+// All other fields are bound like with Command.
+//
+// Instead of RunFuncs, the configuration structs implement the hooks as methods, e.g.
+//
+//	func (c ServeCmd) Run(cmd *cobra.Command, args []string) error
+//
+// which are invoked on a copy of the configuration. PersistentPreRun, PreRun, PostRun and
+// PersistentPostRun are supported as well. Commands without Run only group their sub-commands.
+//
+// The environment prefix of a sub-command is the prefix of its parent plus the screaming snake
+// case of the field name. Like for other fields, an env tag replaces it, and WithFlatEnv makes
+// sub-commands share their parent's prefix. New returns errors like TryCommand.
+func New(envPrefix string, cmd cobra.Command, cli any, opts ...Option) (*cobra.Command, error) {
+	v := reflect.ValueOf(cli)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, &BindError{Msg: "cli must be a struct or struct pointer"}
+	}
+	defaults := reflect.New(v.Type()).Elem()
+	defaults.Set(v)
+	c, envErrs, err := declTree(envPrefix, cmd, defaults, newOptions(opts), envSource{})
+	if err == nil {
+		err = checkEnvNames(c, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, joinEnvErrors(envErrs)
+}
+
+// checkEnvNames returns a *BindError if a field of cmd or its sub-commands is bound to the same
+// environment variable as a field of its parents, which are given by bound. Sibling commands may
+// share variables, as only one of them runs at a time.
+func checkEnvNames(cmd *cobra.Command, bound map[string]string) error {
+	own := maps.Clone(bound)
+	if own == nil {
+		own = make(map[string]string)
+	}
+	for _, field := range Fields(cmd) {
+		path := cmd.CommandPath() + " " + field.Field
+		if field.Env == "" {
+			continue
+		}
+		for _, name := range append([]string{field.Env}, field.EnvAliases...) {
+			if prev, ok := own[name]; ok {
+				return &BindError{Field: field.Field, Msg: fmt.Sprintf("environment variable %s of %s is already bound to %s", name, path, prev)}
+			}
+			own[name] = path
+		}
+	}
+	for _, sub := range cmd.Commands() {
+		if err := checkEnvNames(sub, own); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithFlatEnv makes sub-commands declared via New use the environment prefix of their parent, e.g.
+// FOO_PORT instead of FOO_SERVE_PORT, so that the whole tree shares one namespace. Sub-commands
+// with an env tag keep the prefix given by it.
+func WithFlatEnv() Option {
+	return func(o *options) {
+		o.flatEnv = true
+	}
+}
+
+// declTree creates a declared command and its sub-commands.
+func declTree(envPrefix string, cmd cobra.Command, defaults reflect.Value, o options, env envSource,
+) (*cobra.Command, []*EnvError, error) {
+	c, envErrs, err := declCommand(envPrefix, cmd, defaults, o, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, meta := range typeFields(defaults.Type()) {
+		if meta.cmd == "" {
+			continue
+		}
+		if meta.field.Type.Kind() != reflect.Struct {
+			return nil, nil, &BindError{Field: meta.field.Name, Msg: "sub-command must be a struct"}
+		}
+		name := meta.snake
+		if meta.tags.envPrefix != "" {
+			if err := checkEnvPrefixTag(meta); err != nil {
+				return nil, nil, &BindError{Field: meta.field.Name, Msg: err.Error()}
+			}
+			name = meta.tags.envPrefix
+		}
+		subPrefix := meta.tags.env
+		switch {
+		case subPrefix != "":
+		case o.flatEnv:
+			subPrefix = envPrefix
+		case envPrefix != "":
+			subPrefix = envPrefix + o.envNestingSeparator() + name
+		default:
+			subPrefix = name
+		}
+		subTemplate := cobra.Command{Use: meta.cmd, Short: meta.field.Tag.Get("short")}
+		sub, subEnvErrs, err := declTree(subPrefix, subTemplate, defaults.Field(i), o, env)
+		if err != nil {
+			return nil, nil, err
+		}
+		c.AddCommand(sub)
+		envErrs = append(envErrs, subEnvErrs...)
+	}
+	return c, envErrs, nil
+}
+
+// declCommand creates a single declared command, without its sub-commands.
+func declCommand(envPrefix string, cmd cobra.Command, defaults reflect.Value, o options, env envSource,
+) (*cobra.Command, []*EnvError, error) {
+	template := cmd
+	cfg := reflect.New(defaults.Type())
+	cfg.Elem().Set(defaults)
+
+	envErrs, err := setupCommand(envPrefix, &cmd, cfg.Interface(), o, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	setHooks(&cmd, &cfg, declHooks(defaults.Type()), o)
+	lookupBinding(&cmd).rebuild = func(env envSource, stdout, stderr io.Writer) (*cobra.Command, []*EnvError, error) {
+		cmd := template
+		if stdout != nil {
+			cmd.SetOut(stdout)
+		}
+		if stderr != nil {
+			cmd.SetErr(stderr)
+		}
+		return declCommand(envPrefix, cmd, defaults, o, env)
+	}
+	return &cmd, envErrs, nil
+}
+
+// declHooks returns RunFuncs that invoke the hook methods of a configuration struct of type t.
+// They receive a pointer to the bound configuration, and invoke the methods on a copy of it.
+func declHooks(t reflect.Type) (run RunFuncs[reflect.Value]) {
+	ptrType := reflect.PointerTo(t)
+	hook := func(iface reflect.Type, call func(cfg any, cmd *cobra.Command, args []string) error) RunE[reflect.Value] {
+		if !ptrType.Implements(iface) {
+			return nil
+		}
+		return func(cfg reflect.Value, cmd *cobra.Command, args []string) error {
+			cfgCopy := reflect.New(t)
+			cfgCopy.Elem().Set(cfg.Elem())
+			return call(cfgCopy.Interface(), cmd, args)
+		}
+	}
+	run.PersistentPreRun = hook(reflect.TypeFor[persistentPreRunner](), func(cfg any, cmd *cobra.Command, args []string) error {
+		return cfg.(persistentPreRunner).PersistentPreRun(cmd, args)
+	})
+	run.PreRun = hook(reflect.TypeFor[preRunner](), func(cfg any, cmd *cobra.Command, args []string) error {
+		return cfg.(preRunner).PreRun(cmd, args)
+	})
+	run.Run = hook(reflect.TypeFor[runner](), func(cfg any, cmd *cobra.Command, args []string) error {
+		return cfg.(runner).Run(cmd, args)
+	})
+	run.PostRun = hook(reflect.TypeFor[postRunner](), func(cfg any, cmd *cobra.Command, args []string) error {
+		return cfg.(postRunner).PostRun(cmd, args)
+	})
+	run.PersistentPostRun = hook(reflect.TypeFor[persistentPostRunner](), func(cfg any, cmd *cobra.Command, args []string) error {
+		return cfg.(persistentPostRunner).PersistentPostRun(cmd, args)
+	})
+	return
+}
@@ -0,0 +1,177 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"testing"
+)
+
+type declCLI struct {
+	Verbose bool      `flag:"persistent"`
+	Serve   declServe `cmd:"serve" short:"Start the server"`
+	Admin   declAdmin `cmd:"admin"`
+}
+
+type declServe struct {
+	Port int
+}
+
+var declServed []declServe
+
+func (c *declServe) Run(cmd *cobra.Command, args []string) error {
+	declServed = append(declServed, *c)
+	c.Port = -1 // must not leak into the bound configuration
+	return nil
+}
+
+type declAdmin struct {
+	Token string   `env:"ADMIN_SECRET"`
+	User  declUser `cmd:"user" env:"USERS"`
+}
+
+var errDeclAdmin = errors.New("admin pre-run")
+
+func (c declAdmin) PersistentPreRun(cmd *cobra.Command, args []string) error {
+	if c.Token == "" {
+		return errDeclAdmin
+	}
+	return nil
+}
+
+type declUser struct {
+	Name string
+}
+
+func (c declUser) Run(cmd *cobra.Command, args []string) error {
+	return nil
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("TEST_SERVE_PORT", "8080")
+	t.Setenv("USERS_NAME", "bob")
+
+	root, err := New("TEST", cobra.Command{Use: "app"}, &declCLI{Serve: declServe{Port: 80}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if names := commandNames(root); len(names) != 2 || names[0] != "admin" || names[1] != "serve" {
+		t.Fatalf("expected sub-commands admin and serve, got %v", names)
+	}
+	if root.RunE != nil {
+		t.Error("expected root without Run method to have no RunE")
+	}
+	serve, _, _ := root.Find([]string{"serve"})
+	if serve.Short != "Start the server" {
+		t.Errorf("expected short description from tag, got %q", serve.Short)
+	}
+	if got := lookupBinding(serve).cfg.(*declServe).Port; got != 8080 {
+		t.Errorf("expected TEST_SERVE_PORT to be applied, got %d", got)
+	}
+	if got := root.PersistentFlags().Lookup("serve"); got != nil {
+		t.Error("expected sub-command fields not to be bound as flags")
+	}
+	user, _, _ := root.Find([]string{"admin", "user"})
+	if got := lookupBinding(user).cfg.(*declUser).Name; got != "bob" {
+		t.Errorf("expected env tag to set the prefix of the sub-command, got %q", got)
+	}
+
+	declServed = nil
+	root.SetArgs([]string{"serve", "--verbose", "--port", "9090"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(declServed) != 1 || declServed[0].Port != 9090 {
+		t.Errorf("expected Run to be invoked with parsed configuration, got %v", declServed)
+	}
+	if got := lookupBinding(serve).cfg.(*declServe).Port; got != 9090 {
+		t.Errorf("expected Run to be invoked on a copy of the configuration, got %d", got)
+	}
+}
+
+func TestNew_ParentHooks(t *testing.T) {
+	root, err := New("TEST", cobra.Command{Use: "app"}, declCLI{})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	root.SetArgs([]string{"admin", "user"})
+	root.SetOut(io.Discard)
+	root.SetErr(io.Discard)
+	if err := root.Execute(); !errors.Is(err, errDeclAdmin) {
+		t.Errorf("expected pre-run of parent to run, got %v", err)
+	}
+}
+
+func TestNew_Clone(t *testing.T) {
+	root, err := New("TEST", cobra.Command{Use: "app"}, declCLI{Serve: declServe{Port: 80}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	clone, envErrs, err := cloneTree(root, mapEnv(map[string]string{"TEST_SERVE_PORT": "81"}), nil, nil)
+	if err != nil || len(envErrs) != 0 {
+		t.Fatalf("clone: %v, %v", err, envErrs)
+	}
+	serve, _, _ := clone.Find([]string{"serve"})
+	if got := lookupBinding(serve).cfg.(*declServe).Port; got != 81 {
+		t.Errorf("expected environment of clone to be applied, got %d", got)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	if _, err := New("TEST", cobra.Command{Use: "app"}, 1); err == nil {
+		t.Error("expected error for non-struct")
+	}
+	type badCLI struct {
+		Sub int `cmd:"sub"`
+	}
+	var bindErr *BindError
+	if _, err := New("TEST", cobra.Command{Use: "app"}, badCLI{}); !errors.As(err, &bindErr) {
+		t.Errorf("expected *BindError for non-struct sub-command, got %v", err)
+	}
+	type collidingCLI struct {
+		Port  int
+		Serve struct{ Port int } `cmd:"serve"`
+	}
+	if _, err := New("TEST", cobra.Command{Use: "app"}, collidingCLI{}, WithFlatEnv()); !errors.As(err, &bindErr) ||
+		!strings.Contains(err.Error(), "TEST_PORT of app serve Port is already bound to app Port") {
+		t.Errorf("expected *BindError naming both fields, got %v", err)
+	}
+	t.Setenv("TEST_SERVE_PORT", "x")
+	root, err := New("TEST", cobra.Command{Use: "app"}, declCLI{})
+	var envErr *EnvError
+	if root == nil || !errors.As(err, &envErr) {
+		t.Errorf("expected tree and *EnvError for invalid environment, got %v, %v", root, err)
+	}
+}
+
+func TestNew_EnvPrefixTag(t *testing.T) {
+	type cli struct {
+		Serve declServe `cmd:"serve" envPrefix:"SRV"`
+	}
+	root, err := New("TEST", cobra.Command{Use: "app"}, cli{}, WithEnviron([]string{"TEST_SRV_PORT=8080"}))
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	serve, _, _ := root.Find([]string{"serve"})
+	if got := lookupBinding(serve).cfg.(*declServe).Port; got != 8080 {
+		t.Errorf("expected envPrefix tag to replace the name of the sub-command, got %d", got)
+	}
+}
+
+func TestWithFlatEnv(t *testing.T) {
+	t.Setenv("TEST_PORT", "8080")
+	t.Setenv("USERS_NAME", "bob")
+	root, err := New("TEST", cobra.Command{Use: "app"}, declCLI{}, WithFlatEnv())
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	serve, _, _ := root.Find([]string{"serve"})
+	if got := lookupBinding(serve).cfg.(*declServe).Port; got != 8080 {
+		t.Errorf("expected sub-command to use the prefix of its parent, got %d", got)
+	}
+	user, _, _ := root.Find([]string{"admin", "user"})
+	if got := lookupBinding(user).cfg.(*declUser).Name; got != "bob" {
+		t.Errorf("expected env tag to take precedence, got %q", got)
+	}
+}
@@ -0,0 +1,262 @@
+package nicecmd
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// WithDotEnvDiscovery makes the command read environment variables from the .env file in the
+// working directory, or else in the closest of its parents that has one, as known from direnv.
+// Variables of the environment take precedence over those of the file. A warning names the file
+// when one is found, so that values do not silently come from a forgotten file.
+//
+// Like other options that concern the environment, pass it to all commands of a tree.
+func WithDotEnvDiscovery() Option {
+	return func(o *options) {
+		o.dotEnv.discover = true
+	}
+}
+
+// WithDotEnvLayers extends WithDotEnvDiscovery to the common layering of environment files, with
+// the environment, e.g. "production", taken from the given variable such as APP_ENV. Files are
+// loaded from the closest directory that has any of them, with later ones taking precedence:
+//
+//	.env
+//	.env.<environment>
+//	.env.local
+//	.env.<environment>.local
+//
+// Files of the environment are skipped if the variable is not set. The .local files are meant for
+// overrides that are not committed to version control.
+func WithDotEnvLayers(variable string) Option {
+	return func(o *options) {
+		o.dotEnv.discover = true
+		o.dotEnv.layerVar = variable
+	}
+}
+
+// WithEnvFile makes the command read environment variables from the files matching pattern, in
+// the format of .env files. Patterns as understood by filepath.Glob load all matching files in
+// lexical order, e.g. "conf.d/*.env" for drop-in fragments, while a path without wildcards must
+// exist. The option can be given repeatedly, later files taking precedence, and these files take
+// precedence over discovered ones. Variables of the environment take precedence over all files.
+func WithEnvFile(pattern string) Option {
+	return func(o *options) {
+		o.dotEnv.patterns = append(o.dotEnv.patterns, pattern)
+	}
+}
+
+// WithEnvFileDecrypter makes the command pass the contents of all environment files through
+// decrypt before parsing them, so that they can be committed to version control encrypted, e.g.
+// with age or SOPS. decrypt receives the path of each file, and typically obtains its key from an
+// environment variable or an identity file. It should return data unchanged for files that are not
+// encrypted, if plain files are to be supported as well.
+func WithEnvFileDecrypter(decrypt func(path string, data []byte) ([]byte, error)) Option {
+	return func(o *options) {
+		o.dotEnv.decrypt = decrypt
+	}
+}
+
+// dotEnvOptions are the options that concern environment files.
+type dotEnvOptions struct {
+	discover bool
+	layerVar string   // variable that selects the environment of WithDotEnvLayers
+	patterns []string // patterns of WithEnvFile
+	decrypt  func(path string, data []byte) ([]byte, error)
+}
+
+// dotEnvNames returns the names of the environment files to discover, in order of precedence
+// from lowest to highest.
+func dotEnvNames(environment string, layered bool) []string {
+	switch {
+	case !layered:
+		return []string{".env"}
+	case environment == "":
+		return []string{".env", ".env.local"}
+	default:
+		return []string{".env", ".env." + environment, ".env.local", ".env." + environment + ".local"}
+	}
+}
+
+// dotEnvWarned holds the paths of discovered files that a warning was emitted for, so that a tree
+// of commands warns only once per file.
+var dotEnvWarned sync.Map
+
+// findDotEnv returns the paths of the files with the given names in dir, or else in the closest of
+// its parents that has any of them. The paths are in the order of names.
+func findDotEnv(files fileSystem, dir string, names []string) []string {
+	for {
+		var paths []string
+		for _, name := range names {
+			path := filepath.Join(dir, name)
+			if info, err := files.stat(path); err == nil && !info.IsDir() {
+				paths = append(paths, path)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if len(paths) != 0 || parent == dir {
+			return paths
+		}
+		dir = parent
+	}
+}
+
+// loadEnvFiles makes the variables of the environment files of o available to b as a fallback
+// of its environment. Files that cannot be read or parsed are recorded as *EnvError.
+func (b *binder) loadEnvFiles(o dotEnvOptions) {
+	var paths []string
+	if o.discover {
+		environment := b.env.get(o.layerVar)
+		if strings.ContainsAny(environment, `/\`) || strings.HasPrefix(environment, ".") {
+			b.envErrs = append(b.envErrs, &EnvError{Name: o.layerVar, Value: environment, Err: errors.New("must be a plain name")})
+			return
+		}
+		if dir, err := b.files.getwd(); err == nil {
+			paths = findDotEnv(b.files, dir, dotEnvNames(environment, o.layerVar != ""))
+		}
+		for _, path := range paths {
+			if _, warned := dotEnvWarned.LoadOrStore(path, true); !warned {
+				b.warn.add(DefaultMessages.DotEnvLoaded, path)
+			}
+		}
+	}
+	for _, pattern := range o.patterns {
+		matches, err := b.files.glob(pattern)
+		if err != nil {
+			b.envErrs = append(b.envErrs, &EnvError{File: pattern, Err: err})
+			return
+		}
+		if matches == nil && !hasMeta(pattern) {
+			matches = []string{pattern} // for the error of reading it
+		}
+		paths = append(paths, matches...)
+	}
+	if len(paths) == 0 {
+		return
+	}
+	vars := make(map[string]string)
+	for _, path := range paths {
+		if err := readDotEnv(b.files, path, vars, o.decrypt); err != nil {
+			b.envErrs = append(b.envErrs, &EnvError{File: path, Err: err})
+			return
+		}
+		b.trace("environment file %s loaded", path)
+	}
+	b.env = b.env.withFallback(vars)
+}
+
+// hasMeta reports whether pattern contains any of the wildcards of filepath.Match.
+func hasMeta(pattern string) bool {
+	magic := `*?[`
+	if runtime.GOOS != "windows" {
+		magic = `*?[\`
+	}
+	return strings.ContainsAny(pattern, magic)
+}
+
+// withFallback returns an envSource that looks up variables in e first, and in vars second.
+func (e envSource) withFallback(vars map[string]string) envSource {
+	return envSource{
+		lookup: func(name string) (string, bool) {
+			if value, ok := e.lookup(name); ok {
+				return value, true
+			}
+			value, ok := vars[name]
+			return value, ok
+		},
+		environ: func() []string {
+			environ := e.environ()
+			for name, value := range vars {
+				if _, ok := e.lookup(name); !ok {
+					environ = append(environ, name+"="+value)
+				}
+			}
+			return environ
+		},
+	}
+}
+
+// readDotEnv adds the variables of the environment file at path to vars, decrypting its contents
+// first if decrypt is not nil.
+func readDotEnv(files fileSystem, path string, vars map[string]string, decrypt func(path string, data []byte) ([]byte, error)) error {
+	data, err := files.readFile(path)
+	if err == nil && decrypt != nil {
+		data, err = decrypt(path, data)
+	}
+	if err != nil {
+		return err
+	}
+	return parseDotEnv(data, vars)
+}
+
+// parseDotEnv adds the variables of an environment file to vars. It understands the common
+// subset of the format: NAME=value lines with an optional "export " prefix, comments starting
+// with #, single-quoted values that are taken literally, and double-quoted values with the
+// escapes \n, \r, \t, \" and \\. Values are not expanded, see WithExpansion for that.
+func parseDotEnv(data []byte, vars map[string]string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return fmt.Errorf("line %d: expected NAME=value", n)
+		}
+		value, err := parseDotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", n, name, err)
+		}
+		vars[name] = value
+	}
+	return scanner.Err()
+}
+
+// parseDotEnvValue parses the value of a line of an environment file.
+func parseDotEnvValue(s string) (string, error) {
+	if s == "" || (s[0] != '"' && s[0] != '\'') {
+		if i := strings.Index(s, " #"); i != -1 {
+			s = s[:i]
+		}
+		return strings.TrimSpace(s), nil
+	}
+	quote := s[0]
+	var value strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			if rest := strings.TrimSpace(s[i+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+				return "", errors.New("unexpected text after closing quote")
+			}
+			return value.String(), nil
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				value.WriteByte('\n')
+			case 'r':
+				value.WriteByte('\r')
+			case 't':
+				value.WriteByte('\t')
+			case '"', '\\':
+				value.WriteByte(s[i])
+			default:
+				value.WriteByte('\\')
+				value.WriteByte(s[i])
+			}
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return "", errors.New("unterminated quote")
+}
@@ -0,0 +1,233 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Chdir(wd) })
+}
+
+func TestParseDotEnv(t *testing.T) {
+	vars := map[string]string{"KEPT": "1"}
+	err := parseDotEnv([]byte(`# comment
+PLAIN=value # comment
+export EXPORTED = spaced value
+EMPTY=
+SINGLE='literal \n # not a comment'
+DOUBLE="line\nbreak \"quoted\" \\ \$"
+HASH=a#b
+`), vars)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := map[string]string{
+		"KEPT":     "1",
+		"PLAIN":    "value",
+		"EXPORTED": "spaced value",
+		"EMPTY":    "",
+		"SINGLE":   `literal \n # not a comment`,
+		"DOUBLE":   "line\nbreak \"quoted\" \\ \\$",
+		"HASH":     "a#b",
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("expected %v, got %v", want, vars)
+	}
+
+	for doc, msg := range map[string]string{
+		"A=1\nnot a variable": "line 2: expected NAME=value",
+		"A B=1":               "line 1: expected NAME=value",
+		`A="open`:             "line 1: A: unterminated quote",
+		`A="x" y`:             "line 1: A: unexpected text after closing quote",
+	} {
+		if err := parseDotEnv([]byte(doc), map[string]string{}); err == nil || err.Error() != msg {
+			t.Errorf("expected error %q for %q, got %v", msg, doc, err)
+		}
+	}
+}
+
+func TestWithDotEnvDiscovery(t *testing.T) {
+	dir, err := filepath.EvalSymlinks(t.TempDir()) // as returned by os.Getwd on macOS
+	if err != nil {
+		t.Fatalf("eval symlinks: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("TEST_PORT=8080\nTEST_NAME=file\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	chdir(t, sub)
+
+	var warnings []string
+	WarningHandler = func(cmd *cobra.Command, msg string) {
+		warnings = append(warnings, msg)
+	}
+	defer func() { WarningHandler = nil }()
+
+	for i := 0; i < 2; i++ {
+		cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+			WithEnviron([]string{"TEST_NAME=env"}), WithDotEnvDiscovery())
+		if err != nil {
+			t.Fatalf("command: %v", err)
+		}
+		if cfg := lookupBinding(cmd).cfg.(*envDirConf); cfg.Port != 8080 || cfg.Name != "env" {
+			t.Errorf("expected environment to take precedence over .env of parent directory, got %+v", cfg)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], filepath.Join(dir, ".env")) {
+		t.Errorf("expected a single warning naming the file, got %v", warnings)
+	}
+
+	if err := os.WriteFile(filepath.Join(sub, ".env"), []byte("TEST_PORT='x"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	_, err = TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{}, WithDotEnvDiscovery())
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.File != filepath.Join(sub, ".env") {
+		t.Errorf("expected *EnvError naming the closest file, got %v", err)
+	}
+}
+
+func TestWithDotEnvLayers(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".env":                  "TEST_PORT=1\nTEST_NAME=base\nTEST_PASSWORD=base",
+		".env.production":       "TEST_PORT=2\nTEST_NAME=production",
+		".env.local":            "TEST_PORT=3",
+		".env.production.local": "TEST_PORT=4",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	chdir(t, dir)
+	WarningHandler = func(cmd *cobra.Command, msg string) {}
+	defer func() { WarningHandler = nil }()
+
+	bind := func(environ ...string) (envDirConf, error) {
+		cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+			WithEnviron(environ), WithDotEnvLayers("APP_ENV"))
+		if cmd == nil {
+			return envDirConf{}, err
+		}
+		return *lookupBinding(cmd).cfg.(*envDirConf), err
+	}
+	cfg, err := bind("APP_ENV=production")
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cfg != (envDirConf{Port: 4, Name: "production", Password: "base"}) {
+		t.Errorf("expected layered files of the environment, got %+v", cfg)
+	}
+	if cfg, _ = bind(); cfg.Port != 3 || cfg.Name != "base" {
+		t.Errorf("expected .env and .env.local without environment, got %+v", cfg)
+	}
+	var envErr *EnvError
+	if _, err = bind("APP_ENV=../production"); !errors.As(err, &envErr) || envErr.Name != "APP_ENV" {
+		t.Errorf("expected *EnvError for environment with path, got %v", err)
+	}
+}
+
+func TestWithEnvFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "conf.d"), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	for name, content := range map[string]string{
+		"base.env":        "TEST_PORT=1\nTEST_NAME=base",
+		"conf.d/10-a.env": "TEST_PORT=2\nTEST_PASSWORD=a",
+		"conf.d/20-b.env": "TEST_PORT=3",
+		"conf.d/ignored":  "TEST_PORT=4",
+		"broken/30-c.env": "TEST_PORT",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	bind := func(patterns ...string) (envDirConf, error) {
+		opts := []Option{WithEnviron(nil)}
+		for _, pattern := range patterns {
+			opts = append(opts, WithEnvFile(filepath.Join(dir, pattern)))
+		}
+		cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{}, opts...)
+		if cmd == nil {
+			return envDirConf{}, err
+		}
+		return *lookupBinding(cmd).cfg.(*envDirConf), err
+	}
+
+	cfg, err := bind("base.env", "conf.d/*.env", "none/*.env")
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cfg != (envDirConf{Port: 3, Name: "base", Password: "a"}) {
+		t.Errorf("expected files in order of options, then lexical order, got %+v", cfg)
+	}
+
+	var envErr *EnvError
+	if _, err := bind("conf.d/*.env", "broken/*.env"); !errors.As(err, &envErr) || !strings.HasSuffix(envErr.File, "30-c.env") {
+		t.Errorf("expected *EnvError naming the file that failed to parse, got %v", err)
+	}
+	if _, err := bind("missing.env"); !errors.As(err, &envErr) || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected *EnvError for missing file, got %v", err)
+	}
+}
+
+func TestWithEnvFileDecrypter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret.env")
+	if err := os.WriteFile(path, []byte("GRFG_CNFFJBEQ=uhagre2"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	errKey := errors.New("no identity")
+	rot13 := func(p string, data []byte) ([]byte, error) {
+		if p != path {
+			t.Errorf("expected path of the file, got %q", p)
+		}
+		return []byte(strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'A' && r <= 'Z':
+				return 'A' + (r-'A'+13)%26
+			case r >= 'a' && r <= 'z':
+				return 'a' + (r-'a'+13)%26
+			}
+			return r
+		}, string(data))), nil
+	}
+	cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithEnviron(nil), WithEnvFile(path), WithEnvFileDecrypter(rot13))
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if got := lookupBinding(cmd).cfg.(*envDirConf).Password; got != "hunter2" {
+		t.Errorf("expected decrypted value, got %q", got)
+	}
+
+	_, err = TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithEnviron(nil), WithEnvFile(path), WithEnvFileDecrypter(func(string, []byte) ([]byte, error) {
+			return nil, errKey
+		}))
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.File != path || !errors.Is(err, errKey) {
+		t.Errorf("expected *EnvError for failed decryption, got %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package nicecmd
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	day  = 24 * time.Hour
+	week = 7 * day
+)
+
+// daysValue is the value of a time.Duration flag with encoding:"days". On top of Go's units, it
+// accepts d for days and w for weeks, e.g. 1d12h or 2w, as retention and expiry periods are
+// commonly given in days. A day is always 24 hours.
+type daysValue time.Duration
+
+func newDaysValue(p *time.Duration) *daysValue {
+	return (*daysValue)(p)
+}
+
+func (d *daysValue) Set(s string) error {
+	v, err := parseDays(s)
+	if err != nil {
+		return err
+	}
+	*d = daysValue(v)
+	return nil
+}
+
+func (d *daysValue) String() string {
+	return formatDays(time.Duration(*d))
+}
+
+func (d *daysValue) Type() string {
+	return "duration"
+}
+
+// parseDays parses a duration like time.ParseDuration, but also accepts the units d and w.
+func parseDays(s string) (time.Duration, error) {
+	invalid := fmt.Errorf("invalid duration %q, expected e.g. 90m, 1d12h or 2w", s)
+	tooLarge := fmt.Errorf("invalid duration %q: too large", s)
+	rest, neg := strings.CutPrefix(s, "-")
+	if !neg {
+		rest = strings.TrimPrefix(rest, "+")
+	}
+	if rest == "0" {
+		return 0, nil
+	}
+	if rest == "" {
+		return 0, invalid
+	}
+	var days time.Duration // sum of the components in days and weeks
+	var others []string    // components with Go's units, parsed by time.ParseDuration
+	for rest != "" {
+		numEnd := strings.IndexFunc(rest, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+		if numEnd <= 0 {
+			return 0, invalid // no unit, or no number
+		}
+		unitEnd := numEnd + strings.IndexFunc(rest[numEnd:], func(r rune) bool { return r >= '0' && r <= '9' || r == '.' })
+		if unitEnd < numEnd {
+			unitEnd = len(rest)
+		}
+		num, unit := rest[:numEnd], rest[numEnd:unitEnd]
+		rest = rest[unitEnd:]
+		var size time.Duration
+		switch unit {
+		case "d":
+			size = day
+		case "w":
+			size = week
+		default:
+			others = append(others, num+unit)
+			continue
+		}
+		var d time.Duration
+		if n, err := strconv.ParseInt(num, 10, 64); err == nil {
+			if n > math.MaxInt64/int64(size) {
+				return 0, tooLarge
+			}
+			d = time.Duration(n) * size
+		} else if f, err := strconv.ParseFloat(num, 64); err == nil {
+			if f*float64(size) >= math.MaxInt64 {
+				return 0, tooLarge
+			}
+			d = time.Duration(f * float64(size))
+		} else {
+			return 0, invalid
+		}
+		if d > math.MaxInt64-days {
+			return 0, tooLarge
+		}
+		days += d
+	}
+	var d time.Duration
+	if others != nil {
+		var err error
+		if d, err = time.ParseDuration(strings.Join(others, "")); err != nil {
+			return 0, invalid
+		}
+	}
+	if d > math.MaxInt64-days {
+		return 0, tooLarge
+	}
+	if neg {
+		return -(d + days), nil
+	}
+	return d + days, nil
+}
+
+// formatDays formats d with weeks and days, followed by the rest as formatted by time.Duration,
+// e.g. 1w2d12h0m0s.
+func formatDays(d time.Duration) string {
+	if d > -day && d < day {
+		return d.String()
+	}
+	var s strings.Builder
+	abs := uint64(d)
+	if d < 0 {
+		s.WriteByte('-')
+		abs = uint64(-d) // also right for math.MinInt64, whose negation overflows to itself
+	}
+	if weeks := abs / uint64(week); weeks != 0 {
+		s.WriteString(strconv.FormatUint(weeks, 10) + "w")
+		abs -= weeks * uint64(week)
+	}
+	if days := abs / uint64(day); days != 0 {
+		s.WriteString(strconv.FormatUint(days, 10) + "d")
+		abs -= days * uint64(day)
+	}
+	if abs != 0 {
+		s.WriteString(time.Duration(abs).String())
+	}
+	return s.String()
+}
@@ -0,0 +1,82 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDays(t *testing.T) {
+	for in, want := range map[string]time.Duration{
+		"0":       0,
+		"90m":     90 * time.Minute,
+		"1d":      day,
+		"1d12h":   36 * time.Hour,
+		"2w":      14 * day,
+		"1w2d3h":  9*day + 3*time.Hour,
+		"1.5d":    36 * time.Hour,
+		"-1d":     -day,
+		"+1h1d":   25 * time.Hour,
+		"1d500ms": day + 500*time.Millisecond,
+	} {
+		if got, err := parseDays(in); err != nil || got != want {
+			t.Errorf("%q: expected %s, got %s, %v", in, want, got, err)
+		}
+	}
+	for in, want := range map[string]string{
+		"":                 "expected e.g. 90m, 1d12h or 2w",
+		"1":                "expected e.g. 90m, 1d12h or 2w",
+		"d":                "expected e.g. 90m, 1d12h or 2w",
+		"1y":               "expected e.g. 90m, 1d12h or 2w",
+		"1..5d":            "expected e.g. 90m, 1d12h or 2w",
+		"100000000w":       "too large",
+		"15250w1d2562047h": "too large",
+	} {
+		if _, err := parseDays(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: expected error %q, got %v", in, want, err)
+		}
+	}
+}
+
+func TestFormatDays(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		0:                               "0s",
+		90 * time.Minute:                "1h30m0s",
+		day:                             "1d",
+		36 * time.Hour:                  "1d12h0m0s",
+		-15 * day:                       "-2w1d",
+		math.MinInt64:                   "-15250w1d23h47m16.854775808s",
+		math.MaxInt64:                   "15250w1d23h47m16.854775807s",
+		week + day + time.Nanosecond:    "1w1d1ns",
+		-(week + day + time.Nanosecond): "-1w1d1ns",
+	} {
+		if got := formatDays(d); got != want {
+			t.Errorf("%d: expected %q, got %q", int64(d), want, got)
+		}
+		if parsed, err := parseDays(want); (err != nil || parsed != d) && d != math.MinInt64 {
+			t.Errorf("%q: expected to parse back to %d, got %d, %v", want, int64(d), int64(parsed), err)
+		}
+	}
+}
+
+func TestDaysEncoding(t *testing.T) {
+	type Conf struct {
+		Retention time.Duration `encoding:"days" min:"1d"`
+	}
+	cfg := Conf{Retention: 30 * day}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_RETENTION=2w"})); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Retention != 14*day {
+		t.Errorf("expected 2w from the environment, got %s", cfg.Retention)
+	}
+	if usage := cmd.Flags().FlagUsages(); !strings.Contains(usage, "--retention duration") || !strings.Contains(usage, "(default 4w2d)") {
+		t.Errorf("expected duration flag with default 4w2d, got:\n%s", usage)
+	}
+	if err := cmd.ParseFlags([]string{"--retention", "12h"}); err == nil || !strings.Contains(err.Error(), "must be at least 1d") {
+		t.Errorf("expected error for retention below min, got %v", err)
+	}
+}
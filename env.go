@@ -0,0 +1,265 @@
+package nicecmd
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envSource abstracts access to environment variables, so that commands can be bound against an
+// environment other than the process environment. The zero value stands for the environment of
+// the command's options, see WithEnviron and WithLookupEnv.
+type envSource struct {
+	lookup  func(name string) (string, bool)
+	environ func() []string
+}
+
+var osEnv = envSource{lookup: os.LookupEnv, environ: os.Environ}
+
+// mapEnv returns an envSource for a fixed set of variables.
+func mapEnv(env map[string]string) envSource {
+	return envSource{
+		lookup: func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		},
+		environ: func() []string {
+			environ := make([]string, 0, len(env))
+			for name, value := range env {
+				environ = append(environ, name+"="+value)
+			}
+			return environ
+		},
+	}
+}
+
+// WithEnviron binds the command against a snapshot of environment variables in the "NAME=value"
+// form of os.Environ, instead of the process environment. Like for os.Getenv, later entries take
+// precedence over earlier ones. Use it to bind an environment that was received over RPC or parsed
+// from a file, or to run tests without os.Setenv. The environment is kept by Clone, while Resolve
+// and nicecmdtest use their own if one is given.
+func WithEnviron(environ []string) Option {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if name, value, ok := strings.Cut(kv, "="); ok {
+			env[name] = value
+		}
+	}
+	return func(o *options) {
+		o.env = mapEnv(env)
+	}
+}
+
+// WithLookupEnv binds the command against environment variables returned by lookup, which has
+// the signature of os.LookupEnv. As lookup cannot enumerate variables, nicecmd does not suggest
+// a prefix when none of the command's variables are set. See WithEnviron for how it interacts with
+// Clone and Resolve.
+func WithLookupEnv(lookup func(name string) (value string, ok bool)) Option {
+	return func(o *options) {
+		o.env = envSource{lookup: lookup, environ: func() []string { return nil }}
+	}
+}
+
+// WithCaseInsensitiveEnv matches environment variables regardless of case, e.g. MyApp_Port for
+// MYAPP_PORT. Variables that match exactly take precedence. os.LookupEnv already ignores case on
+// Windows, and the option extends this to other platforms, to WithEnviron, and to the hint about a
+// similar prefix.
+func WithCaseInsensitiveEnv() Option {
+	return func(o *options) {
+		o.envFold = true
+	}
+}
+
+// fold returns an envSource that matches names regardless of case, based on a snapshot of the
+// variables. Its environ returns them with uppercase names.
+func (e envSource) fold() envSource {
+	folded := make(map[string]string)
+	var environ []string
+	for _, kv := range e.environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		upper := strings.ToUpper(name)
+		if _, ok := folded[upper]; !ok {
+			folded[upper] = value
+			environ = append(environ, upper+"="+value)
+		}
+	}
+	return envSource{
+		lookup: func(name string) (string, bool) {
+			if value, ok := e.lookup(name); ok {
+				return value, true
+			}
+			value, ok := folded[strings.ToUpper(name)]
+			return value, ok
+		},
+		environ: func() []string {
+			return environ
+		},
+	}
+}
+
+// get returns the value of an environment variable, treating empty values as unset.
+func (e envSource) get(name string) string {
+	value, _ := e.lookup(name)
+	return value
+}
+
+// WithEnvironment enables or disables environment variables for the command, regardless of the
+// global Environment, e.g. to bind the same configuration twice in parallel tests, or for a command
+// tree embedded in a program that uses another library for environment variables.
+func WithEnvironment(enabled bool) Option {
+	return func(o *options) {
+		o.environment = &enabled
+	}
+}
+
+// WithEnvNestingSeparator replaces the underscore that joins the environment prefix, the names of
+// nested structs, and the names of fields, e.g. "__" for FOO__SERVER__PORT instead of
+// FOO_SERVER_PORT. This keeps the mapping unambiguous when names contain underscores themselves.
+// Sub-commands declared via New use the separator for their prefixes as well.
+func WithEnvNestingSeparator(sep string) Option {
+	return func(o *options) {
+		o.envSep = sep
+	}
+}
+
+// envNestingSeparator returns the separator of WithEnvNestingSeparator, or the default "_".
+func (o options) envNestingSeparator() string {
+	if o.envSep == "" {
+		return "_"
+	}
+	return o.envSep
+}
+
+// WithEnvDir makes the command read environment variables that are not set from files named after
+// them in dir, e.g. from a Kubernetes ConfigMap or Secret that is mounted with one file per key. A
+// single trailing newline is removed from their contents. The option can be given repeatedly, and
+// earlier directories take precedence.
+func WithEnvDir(dir string) Option {
+	return func(o *options) {
+		o.envDirs = append(o.envDirs, dir)
+	}
+}
+
+// WithEnvFileSuffix makes the command read an environment variable that is not set from the file
+// that the variable with the given suffix points to, following the convention of Docker images. For
+// example, with the suffix "_FILE", FOO_PASSWORD_FILE=/run/secrets/password makes nicecmd read the
+// value of FOO_PASSWORD from that file, minus one trailing newline. These variables take precedence
+// over the directories of WithEnvDir.
+//
+// This is opt-in, as variables such as FOO_CERT_FILE frequently refer to files in their own right.
+func WithEnvFileSuffix(suffix string) Option {
+	return func(o *options) {
+		o.envFileSuffix = suffix
+	}
+}
+
+// lookupEnv returns the value of an environment variable, falling back to WithEnvFileSuffix and
+// the directories of WithEnvDir. Empty values are treated as unset. The error is an *EnvError for
+// files that are referenced by a variable, but cannot be read, or files of WithEnvDir that exist
+// but cannot be read.
+func (b *binder) lookupEnv(name string) (string, *EnvError) {
+	if value := b.env.get(name); value != "" {
+		return value, nil
+	}
+	if b.envFileSuffix != "" {
+		if path := b.env.get(name + b.envFileSuffix); path != "" {
+			value, err := readEnvFile(b.files, path)
+			if err != nil {
+				return "", &EnvError{Name: name + b.envFileSuffix, Value: path, Err: err}
+			}
+			return value, nil
+		}
+	}
+	for _, dir := range b.envDirs {
+		value, err := readEnvFile(b.files, filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return "", &EnvError{Name: name, Err: err}
+		}
+		return value, nil
+	}
+	return "", nil
+}
+
+// lookupEnvList returns the values of the indexed environment variables NAME_0, NAME_1, and so on,
+// up to the first one that is not set, or nil if there are none. They are looked up like with
+// lookupEnv, and are recorded as consulted for hintEnvPrefix.
+func (b *binder) lookupEnvList(name string) ([]string, *EnvError) {
+	var values []string
+	for i := 0; ; i++ {
+		indexed := name + "_" + strconv.Itoa(i)
+		value, err := b.lookupEnv(indexed)
+		if err != nil || value == "" {
+			return values, err
+		}
+		b.envNames = append(b.envNames, indexed)
+		values = append(values, value)
+	}
+}
+
+// lookupEnvMap returns the environment variables that start with NAME_ as key=value pairs in the
+// CSV format of map flags, with the rest of their name as key. Keys are lowercased if lower is set.
+// It returns an empty string if there are no such variables.
+func (b *binder) lookupEnvMap(name string, lower bool) string {
+	var pairs []string
+	for _, kv := range b.env.environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		key, ok := strings.CutPrefix(key, name+"_")
+		if !ok || key == "" || value == "" {
+			continue
+		}
+		b.envNames = append(b.envNames, name+"_"+key)
+		if lower {
+			key = strings.ToLower(key)
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	sort.Strings(pairs)
+	var sb strings.Builder
+	w := csv.NewWriter(&sb)
+	_ = w.Write(pairs)
+	w.Flush()
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// fileRefPrefix marks values of environment variables that refer to a file holding the value.
+const fileRefPrefix = "file://"
+
+// readEnvFileRef reads the value of an environment variable from the file that a file:// URL
+// refers to, e.g. file:///run/secrets/key.
+func readEnvFileRef(files fileSystem, ref string) (string, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", err
+	}
+	if u.Host != "" && u.Host != "localhost" {
+		return "", fmt.Errorf("file URL %q must refer to a local file", ref)
+	}
+	path := filepath.FromSlash(u.Path)
+	if runtime.GOOS == "windows" {
+		path = strings.TrimPrefix(path, `\`) // file:///C:/key
+	}
+	return readEnvFile(files, path)
+}
+
+// readEnvFile reads the value of an environment variable from a file.
+func readEnvFile(files fileSystem, path string) (string, error) {
+	data, err := files.readFile(path)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimSuffix(string(data), "\n")
+	return strings.TrimSuffix(value, "\r"), nil
+}
@@ -0,0 +1,253 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type envDirConf struct {
+	Port     int
+	Name     string
+	Password string `flag:"secret"`
+}
+
+func TestWithEnvDir(t *testing.T) {
+	dir, other := t.TempDir(), t.TempDir()
+	for path, content := range map[string]string{
+		filepath.Join(dir, "TEST_PORT"):       "8080\n",
+		filepath.Join(dir, "TEST_NAME"):       "file",
+		filepath.Join(other, "TEST_PASSWORD"): "hunter2\r\n",
+		filepath.Join(other, "TEST_PORT"):     "9090",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	t.Setenv("TEST_NAME", "env")
+
+	cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithEnvDir(dir), WithEnvDir(other))
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	cfg := lookupBinding(cmd).cfg.(*envDirConf)
+	if *cfg != (envDirConf{Port: 8080, Name: "env", Password: "hunter2"}) {
+		t.Errorf("expected env > first dir > second dir, got %+v", *cfg)
+	}
+}
+
+func TestWithEnvDir_Unreadable(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "TEST_PORT"), 0o700); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	_, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{}, WithEnvDir(dir))
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_PORT" {
+		t.Errorf("expected *EnvError for unreadable file, got %v", err)
+	}
+}
+
+func TestWithEnvFileSuffix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "password")
+	if err := os.WriteFile(path, []byte("hunter2\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	t.Setenv("TEST_PASSWORD_FILE", path)
+	t.Setenv("TEST_NAME_FILE", path)
+	t.Setenv("TEST_NAME", "env")
+
+	cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithEnvFileSuffix("_FILE"))
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	cfg := lookupBinding(cmd).cfg.(*envDirConf)
+	if cfg.Password != "hunter2" || cfg.Name != "env" {
+		t.Errorf("expected file to be read unless the variable is set, got %+v", *cfg)
+	}
+
+	cmd, err = TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{})
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cfg := lookupBinding(cmd).cfg.(*envDirConf); cfg.Password != "" {
+		t.Errorf("expected files to be ignored without the option, got %q", cfg.Password)
+	}
+
+	t.Setenv("TEST_PASSWORD_FILE", filepath.Join(dir, "missing"))
+	_, err = TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithEnvFileSuffix("_FILE"))
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_PASSWORD_FILE" || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected *EnvError naming the _FILE variable, got %v", err)
+	}
+}
+
+func TestWithEnvironment(t *testing.T) {
+	t.Setenv("TEST_PORT", "8080")
+	bind := func(opts ...Option) int {
+		cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{}, opts...)
+		if err != nil {
+			t.Fatalf("command: %v", err)
+		}
+		if fields := Fields(cmd); (fields[0].Env != "") != (lookupBinding(cmd).cfg.(*envDirConf).Port != 0) {
+			t.Errorf("expected FieldInfo.Env to reflect whether environment variables are used, got %+v", fields[0])
+		}
+		return lookupBinding(cmd).cfg.(*envDirConf).Port
+	}
+	if got := bind(WithEnvironment(false)); got != 0 {
+		t.Errorf("expected environment to be disabled by option, got %d", got)
+	}
+
+	defer func(enabled bool) { Environment = enabled }(Environment)
+	Environment = false
+	if got := bind(); got != 0 {
+		t.Errorf("expected environment to be disabled globally, got %d", got)
+	}
+	if got := bind(WithEnvironment(true)); got != 8080 {
+		t.Errorf("expected option to take precedence over global setting, got %d", got)
+	}
+}
+
+func TestWithEnviron(t *testing.T) {
+	t.Setenv("TEST_PORT", "1")
+	cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithEnviron([]string{"TEST_PORT=8080", "TEST_PASSWORD=a=b", "TEST_PORT=8081"}))
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cfg := lookupBinding(cmd).cfg.(*envDirConf); cfg.Port != 8081 || cfg.Password != "a=b" {
+		t.Errorf("expected snapshot instead of process environment, got %+v", cfg)
+	}
+
+	clone, err := Clone(cmd)
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if got := lookupBinding(clone).cfg.(*envDirConf).Port; got != 8081 {
+		t.Errorf("expected clone to keep the snapshot, got %d", got)
+	}
+	cfg, err := Resolve[envDirConf](cmd, nil, map[string]string{"TEST_PORT": "9090"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Port != 9090 {
+		t.Errorf("expected environment of Resolve to take precedence, got %d", cfg.Port)
+	}
+}
+
+func TestWithLookupEnv(t *testing.T) {
+	var looked []string
+	cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		WithLookupEnv(func(name string) (string, bool) {
+			looked = append(looked, name)
+			return "8080", name == "TEST_PORT"
+		}))
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if got := lookupBinding(cmd).cfg.(*envDirConf).Port; got != 8080 {
+		t.Errorf("expected lookup to be used, got %d", got)
+	}
+	if !slices.Contains(looked, "TEST_PASSWORD") {
+		t.Errorf("expected all variables to be looked up, got %v", looked)
+	}
+}
+
+func TestWithCaseInsensitiveEnv(t *testing.T) {
+	environ := WithEnviron([]string{"Test_Port=8080", "test_name=lower", "TEST_NAME=exact"})
+	cmd, err := TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{},
+		environ, WithCaseInsensitiveEnv())
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cfg := lookupBinding(cmd).cfg.(*envDirConf); cfg.Port != 8080 || cfg.Name != "exact" {
+		t.Errorf("expected variables to match regardless of case, got %+v", cfg)
+	}
+
+	cmd, err = TryCommand("TEST", RunFuncs[envDirConf]{}, cobra.Command{Use: "test"}, envDirConf{}, environ)
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if cfg := lookupBinding(cmd).cfg.(*envDirConf); cfg.Port != 0 {
+		t.Errorf("expected case-sensitive matching by default, got %+v", cfg)
+	}
+}
+
+func TestWithEnvNestingSeparator(t *testing.T) {
+	type serverConf struct {
+		ListenPort int
+	}
+	type nestedConf struct {
+		Server serverConf
+	}
+	var cfg nestedConf
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnvNestingSeparator("__"),
+		WithEnviron([]string{"TEST_SERVER_LISTEN_PORT=1", "TEST__SERVER__LISTEN_PORT=8080"}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Server.ListenPort != 8080 {
+		t.Errorf("expected nested names joined by the separator, got %+v", cfg)
+	}
+}
+
+func TestEnvFileRef(t *testing.T) {
+	key := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(key, []byte("-----BEGIN KEY-----\nabc\n-----END KEY-----\n"), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	ref := "file://" + filepath.ToSlash(key)
+	if !strings.HasPrefix(ref, "file:///") {
+		ref = "file:///" + filepath.ToSlash(key) // Windows
+	}
+	type refConf struct {
+		Key      string   `flag:"secret"`
+		Endpoint string   `flag:"literal"`
+		Keys     []string `envSeparator:";"`
+	}
+	var cfg refConf
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{
+		"TEST_KEY=" + ref,
+		"TEST_ENDPOINT=file:///srv/data",
+		"TEST_KEYS_0=" + ref,
+		"TEST_KEYS_1=plain",
+	}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := "-----BEGIN KEY-----\nabc\n-----END KEY-----"
+	if cfg.Key != want || cfg.Endpoint != "file:///srv/data" || !slices.Equal(cfg.Keys, []string{want, "plain"}) {
+		t.Errorf("expected file references to be resolved unless literal, got %+v", cfg)
+	}
+	if usage := cmd.Flags().Lookup("keys").Usage; strings.Contains(usage, "BEGIN") {
+		t.Errorf("expected usage to show the reference instead of the contents, got %q", usage)
+	}
+
+	for _, value := range []string{"file://host/key", "file:///missing/key"} {
+		var envErr *EnvError
+		if err := TryBindConfig("TEST", &cobra.Command{}, &refConf{}, WithEnviron([]string{"TEST_KEYS=" + value})); !errors.As(err, &envErr) || envErr.Name != "TEST_KEYS" {
+			t.Errorf("expected *EnvError for %s, got %v", value, err)
+		}
+	}
+}
+
+func TestEmptyEnvIsUnset(t *testing.T) {
+	cfg := envDirConf{Port: 80, Name: "default"}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_PORT=", "TEST_NAME="})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Port != 80 || cfg.Name != "default" || cmd.Flags().Lookup("name").Changed {
+		t.Errorf("expected empty variables to keep defaults, got %+v", cfg)
+	}
+}
@@ -0,0 +1,88 @@
+package nicecmd
+
+import (
+	"strings"
+)
+
+// hintEnvPrefix warns if none of the bound environment variables are set, but variables that only
+// differ by a similar prefix are, e.g. MY_APP_FOO for MYAPP_FOO. This commonly happens after a
+// binary was renamed. The environment is only listed if the command binds variables with the
+// prefix, as listing it is the main cost for trees of many commands that bind few variables each.
+func (b *binder) hintEnvPrefix(envPrefix string, environ func() []string) {
+	if envPrefix == "" {
+		return
+	}
+	// Index the suffixes of bound variables, so that each variable of a potentially huge
+	// environment is only split at its underscores instead of being compared with every flag.
+	suffixes := make(map[string]int, len(b.envNames))
+	for _, bound := range b.envNames {
+		if suffix, ok := strings.CutPrefix(bound, envPrefix); ok {
+			suffixes[suffix]++
+		} // else custom env tag without prefix
+	}
+	if len(suffixes) == 0 {
+		return
+	}
+	similar := make(map[string]bool)
+	counts := make(map[string]int)
+	for _, kv := range environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		for i := 0; i < len(name); i++ {
+			if name[i] != '_' {
+				continue
+			}
+			prefix, suffix := name[:i+1], name[i+1:]
+			n := suffixes[suffix]
+			if n == 0 || prefix == envPrefix {
+				continue
+			}
+			isSimilar, ok := similar[prefix]
+			if !ok {
+				isSimilar = similarEnvPrefix(prefix, envPrefix)
+				similar[prefix] = isSimilar
+			}
+			if isSimilar {
+				counts[prefix] += n
+			}
+		}
+	}
+	best, bestCount := "", 0
+	for prefix, count := range counts {
+		if count > bestCount || (count == bestCount && prefix < best) {
+			best, bestCount = prefix, count
+		}
+	}
+	if bestCount != 0 {
+		b.warn.add(DefaultMessages.EnvPrefix, envPrefix, bestCount, best)
+	}
+}
+
+// similarEnvPrefix reports whether two prefixes, both ending with an underscore, only differ by
+// underscores or a typo. Short prefixes must match exactly apart from underscores.
+func similarEnvPrefix(a, b string) bool {
+	if !strings.HasSuffix(a, "_") {
+		return false
+	}
+	a, b = strings.ReplaceAll(a, "_", ""), strings.ReplaceAll(b, "_", "")
+	return a == b || levenshtein(a, b) <= min(2, len(b)/4)
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
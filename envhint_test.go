@@ -0,0 +1,78 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"slices"
+	"testing"
+)
+
+func TestBinder_HintEnvPrefix(t *testing.T) {
+	tt := []struct {
+		name    string
+		environ []string
+		want    []string
+	}{
+		{name: "underscore", environ: []string{"MY_APP_FOO=1", "MY_APP_BAR=2", "OTHER_FOO=3"}, want: []string{
+			"no environment variable with prefix MYAPP_ is set, but variables with the similar prefix MY_APP_ are (2)",
+		}},
+		{name: "typo", environ: []string{"MYAP_FOO=1"}, want: []string{
+			"no environment variable with prefix MYAPP_ is set, but variables with the similar prefix MYAP_ are (1)",
+		}},
+		{name: "nested", environ: []string{"MY_APP_LOG_LEVEL=debug"}, want: []string{
+			"no environment variable with prefix MYAPP_ is set, but variables with the similar prefix MY_APP_ are (1)",
+		}},
+		{name: "unrelated", environ: []string{"GO_FOO=1", "FOO=2", "XFOO=3"}, want: nil},
+	}
+	for _, test := range tt {
+		t.Run(test.name, func(t *testing.T) {
+			b := &binder{cmd: &cobra.Command{}, envNames: []string{"MYAPP_FOO", "MYAPP_BAR", "MYAPP_LOG_LEVEL", "CUSTOM"}}
+			b.hintEnvPrefix("MYAPP_", func() []string { return test.environ })
+			if !slices.Equal(b.warn.msgs, test.want) {
+				t.Errorf("unexpected hints, want %q, got %q", test.want, b.warn.msgs)
+			}
+		})
+	}
+}
+
+func TestBinder_HintEnvPrefix_Unbound(t *testing.T) {
+	b := &binder{cmd: &cobra.Command{}, envNames: []string{"CUSTOM"}}
+	b.hintEnvPrefix("MYAPP_", func() []string {
+		t.Error("expected environment not to be listed without variables of the prefix")
+		return nil
+	})
+}
+
+func BenchmarkBinder_HintEnvPrefix(b *testing.B) {
+	environ := make([]string, 5000)
+	for i := range environ {
+		environ[i] = fmt.Sprintf("CI_RUNNER_SOME_LONG_VARIABLE_%d=value", i)
+	}
+	envNames := make([]string, 200)
+	for i := range envNames {
+		envNames[i] = fmt.Sprintf("MYAPP_SUB_FIELD_%d", i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		binder := &binder{cmd: &cobra.Command{}, envNames: envNames}
+		binder.hintEnvPrefix("MYAPP_", func() []string { return environ })
+	}
+}
+
+func Test_levenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"MYAPP", "MYAP", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package nicecmd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// BindError reports a command or configuration struct that cannot be set up, typically because of
+// a mistake in its struct tags. It indicates a programming error rather than a user error.
+type BindError struct {
+	Field string // Go path of the offending field, e.g. "Log.Level", if any
+	Msg   string
+}
+
+func (e *BindError) Error() string {
+	if e.Field == "" {
+		return e.Msg
+	}
+	return fmt.Sprintf("field %s: %s", e.Field, e.Msg)
+}
+
+// EnvError reports an environment variable whose value could not be applied to its flag. For
+// secret flags, neither the value nor the underlying error are retained, as the latter would
+// typically contain the value as well. For environment files that cannot be read or parsed, File
+// is set instead of Name.
+type EnvError struct {
+	Name   string
+	Value  string
+	Err    error
+	Secret bool
+	File   string
+}
+
+func (e *EnvError) Error() string {
+	if e.File != "" {
+		return fmt.Sprintf(DefaultMessages.InvalidEnvFile, e.File, e.Err)
+	}
+	if e.Secret {
+		return fmt.Sprintf(DefaultMessages.InvalidSecretEnv, e.Name)
+	}
+	return fmt.Sprintf(DefaultMessages.InvalidEnv, e.Name, e.Err)
+}
+
+func (e *EnvError) Unwrap() error {
+	return e.Err
+}
+
+func joinEnvErrors(envErrs []*EnvError) error {
+	errs := make([]error, len(envErrs))
+	for i, envErr := range envErrs {
+		errs[i] = envErr
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,135 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/pflag"
+	"os"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// WithExpansion makes the command expand references to environment variables of the form ${NAME}
+// in values of environment variables and configuration files, e.g. DATA_DIR=${HOME}/data. Values
+// of referenced variables are expanded as well, and references that form a cycle are reported as
+// an error. Variables that are not set expand to an empty string. Write $$ for a literal $.
+//
+// Flag values given on the command line are not expanded, as the shell already does that.
+func WithExpansion() Option {
+	return func(o *options) {
+		o.expand = true
+	}
+}
+
+// expander expands references to environment variables, see WithExpansion.
+type expander struct {
+	env envSource
+}
+
+func (e expander) expand(value string) (string, error) {
+	return e.expandRefs(value, nil)
+}
+
+// expandRefs expands value, which is the value of the variables in stack, innermost last.
+func (e expander) expandRefs(value string, stack []string) (string, error) {
+	if !strings.Contains(value, "$") {
+		return value, nil
+	}
+	var s strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '$' || i+1 == len(value) {
+			s.WriteByte(value[i])
+		} else if value[i+1] == '$' {
+			s.WriteByte('$')
+			i++
+		} else if value[i+1] == '{' {
+			end := strings.IndexByte(value[i+2:], '}')
+			if end == -1 {
+				return "", fmt.Errorf("unterminated reference %q", value[i:])
+			}
+			name := value[i+2 : i+2+end]
+			if name == "" {
+				return "", fmt.Errorf("empty reference ${}")
+			}
+			if slices.Contains(stack, name) {
+				cycle := append(stack[slices.Index(stack, name):], name)
+				return "", fmt.Errorf("reference cycle %s", strings.Join(cycle, " -> "))
+			}
+			ref, _ := e.env.lookup(name)
+			expanded, err := e.expandRefs(ref, append(stack, name))
+			if err != nil {
+				return "", err
+			}
+			s.WriteString(expanded)
+			i += 2 + end
+		} else {
+			s.WriteByte('$')
+		}
+	}
+	return s.String(), nil
+}
+
+// expandPathTag is the only value of the expand tag, e.g. `expand:"path"`.
+const expandPathTag = "path"
+
+// pathValue expands the values of a flag with expand:"path" before setting them, so that values
+// from flags, environment variables, environment files and configuration files are all expanded.
+type pathValue struct {
+	pflag.Value
+	env envSource
+}
+
+func (v *pathValue) Set(s string) error {
+	path, err := expandPath(s, v.env)
+	if err != nil {
+		return err
+	}
+	return v.Value.Set(path)
+}
+
+// expandPaths makes param expand ~, ~user, $NAME and ${NAME} in its values, and expands the
+// field's current value, i.e. its default.
+func (b *binder) expandPaths(param *pflag.Flag, field reflect.Value, tags fieldTags) error {
+	if tags.expand != expandPathTag {
+		return fmt.Errorf(`expected expand:"path", got %q`, tags.expand)
+	}
+	if field.Kind() != reflect.String {
+		return fmt.Errorf("expand requires a string, got %s", field.Type())
+	}
+	path, err := expandPath(field.String(), b.env)
+	if err != nil {
+		return fmt.Errorf("default value: %w", err)
+	}
+	field.SetString(path)
+	param.Value = &pathValue{Value: param.Value, env: b.env}
+	return nil
+}
+
+// expandPath expands a leading ~ or ~user to the home directory, and references to environment
+// variables. Variables that are not set expand to an empty string.
+func expandPath(path string, env envSource) (string, error) {
+	path = os.Expand(path, func(name string) string {
+		value, _ := env.lookup(name)
+		return value
+	})
+	if !strings.HasPrefix(path, "~") {
+		return path, nil
+	}
+	name, rest, _ := strings.Cut(path[1:], string(filepath.Separator))
+	var home string
+	if name == "" {
+		var err error
+		if home, err = os.UserHomeDir(); err != nil {
+			return "", err
+		}
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return "", err
+		}
+		home = u.HomeDir
+	}
+	return filepath.Join(home, rest), nil
+}
@@ -0,0 +1,134 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpander(t *testing.T) {
+	e := expander{env: mapEnv(map[string]string{
+		"HOME":  "/home/gopher",
+		"DATA":  "${HOME}/data",
+		"A":     "${B}",
+		"B":     "x${C}",
+		"C":     "${A}",
+		"PRICE": "$$5",
+	})}
+	for in, want := range map[string]string{
+		"${HOME}/data":  "/home/gopher/data",
+		"${DATA}/cache": "/home/gopher/data/cache",
+		"${UNSET}x":     "x",
+		"$$HOME":        "$HOME",
+		"${PRICE}":      "$5",
+		"$HOME $":       "$HOME $",
+		"plain":         "plain",
+	} {
+		if got, err := e.expand(in); err != nil || got != want {
+			t.Errorf("expand(%q): expected %q, got %q, %v", in, want, got, err)
+		}
+	}
+	for in, want := range map[string]string{
+		"${A}":    "reference cycle A -> B -> C -> A",
+		"${HOME":  "unterminated reference",
+		"x${}":    "empty reference",
+		"${C}/${": "reference cycle C -> A -> B -> C",
+	} {
+		if _, err := e.expand(in); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expand(%q): expected error %q, got %v", in, want, err)
+		}
+	}
+}
+
+func TestWithExpansion(t *testing.T) {
+	type Conf struct {
+		DataDir string
+		Cache   string `flag:"secret"`
+	}
+	t.Setenv("TEST_HOME", "/home/gopher")
+	t.Setenv("TEST_DATA_DIR", "${TEST_HOME}/data")
+	cmd, err := TryCommand("TEST", RunFuncs[Conf]{}, cobra.Command{Use: "test"}, Conf{}, WithExpansion())
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if got := lookupBinding(cmd).cfg.(*Conf).DataDir; got != "/home/gopher/data" {
+		t.Errorf("expected expanded value, got %q", got)
+	}
+	if usage := cmd.Flags().Lookup("data-dir").Usage; !strings.Contains(usage, `"/home/gopher/data"`) {
+		t.Errorf("expected usage to show expanded value, got %q", usage)
+	}
+
+	cmd, err = TryCommand("TEST", RunFuncs[Conf]{}, cobra.Command{Use: "test"}, Conf{})
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	if got := lookupBinding(cmd).cfg.(*Conf).DataDir; got != "${TEST_HOME}/data" {
+		t.Errorf("expected no expansion without option, got %q", got)
+	}
+
+	t.Setenv("TEST_CACHE", "${TEST_CACHE}")
+	_, err = TryCommand("TEST", RunFuncs[Conf]{}, cobra.Command{Use: "test"}, Conf{}, WithExpansion())
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_CACHE" || !envErr.Secret {
+		t.Errorf("expected redacted *EnvError for cycle, got %v", err)
+	}
+}
+
+func TestWithExpansion_Config(t *testing.T) {
+	cmd := Command("TEST", RunFuncs[configConf]{}, cobra.Command{Use: "test"}, configConf{},
+		WithConfigFlag("config"), WithExpansion())
+	path := writeConfig(t, "app.json", `{"user": "${USER}", "tags": ["${USER}", "b"]}`)
+	cfg, err := Resolve[configConf](cmd, []string{"--config", path}, map[string]string{"USER": "bob"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.User != "bob" || len(cfg.Tags) != 2 || cfg.Tags[0] != "bob" {
+		t.Errorf("expected expanded values of configuration file, got %+v", cfg)
+	}
+}
+
+func TestExpandPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	type Conf struct {
+		DataDir  string `expand:"path"`
+		CacheDir string `expand:"path"`
+		LogDir   string `expand:"path"`
+		Literal  string
+	}
+	cfg := Conf{CacheDir: "~/.cache", Literal: "~/x"}
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{
+		"TEST_LOG_DIR=$STATE/log",
+		"TEST_LITERAL=~/y",
+		"STATE=/var/lib/test",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--data-dir", "~/data"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := Conf{
+		DataDir:  filepath.Join(home, "data"),
+		CacheDir: filepath.Join(home, ".cache"),
+		LogDir:   "/var/lib/test/log",
+		Literal:  "~/y",
+	}
+	if cfg != want {
+		t.Errorf("expected %+v, got %+v", want, cfg)
+	}
+	if def := cmd.Flags().Lookup("cache-dir").DefValue; def != "~/.cache" {
+		t.Errorf("expected the default to be shown as given, got %q", def)
+	}
+
+	var bindErr *BindError
+	err = TryBindConfig("TEST", &cobra.Command{}, &struct {
+		Port int `expand:"path"`
+	}{}, WithEnviron(nil))
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected *BindError for expanding an int, got %v", err)
+	}
+}
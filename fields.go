@@ -0,0 +1,51 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"reflect"
+	"slices"
+)
+
+// FieldInfo describes how a field of a configuration struct was bound to a flag. It is meant for
+// documentation generators, GUIs and audits, which can use it instead of re-interpreting tags.
+type FieldInfo struct {
+	Field         string       // Go path of the field, e.g. "Log.Level"
+	Type          reflect.Type // type of the field
+	Flag          string       // flag name without dashes
+	Shorthand     string       // single-letter shorthand, if any
+	Aliases       []string     // hidden flags of the aliases tag that set the same value
+	Env           string       // bound environment variable, empty if none
+	EnvAliases    []string     // further environment variables bound via env:"A|B", by precedence
+	EnvDeprecated []string     // former environment variables that are still read, with a warning
+	Default       string       // default value as shown by pflag, redacted for secrets
+	Usage         string       // usage from the field's tag, without nicecmd's annotations
+	Deprecated    string       // deprecation notice of the flag, empty if it is not deprecated
+	Required      bool
+	Persistent    bool
+	Secret        bool
+	Hidden        bool
+}
+
+// Fields returns the fields bound to cmd in declaration order, or nil if cmd was not set up by
+// Command or BindConfig. Only cmd's own fields are returned, not those inherited from parents.
+func Fields(cmd *cobra.Command) []FieldInfo {
+	if b := lookupBinding(cmd); b != nil {
+		return slices.Clone(b.fields)
+	}
+	return nil
+}
+
+// Walk calls fn for root and all of its sub-commands, depth-first and in the order returned by
+// Command.Commands, along with the fields bound to each command. It stops at the first error
+// returned by fn and returns it.
+func Walk(root *cobra.Command, fn func(cmd *cobra.Command, fields []FieldInfo) error) error {
+	if err := fn(root, Fields(root)); err != nil {
+		return err
+	}
+	for _, sub := range root.Commands() {
+		if err := Walk(sub, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
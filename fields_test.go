@@ -0,0 +1,66 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"reflect"
+	"testing"
+)
+
+func TestFields(t *testing.T) {
+	type LogConf struct {
+		Level string `param:"level,l" usage:"log level"`
+	}
+	type Conf struct {
+		Log   LogConf `flag:"persistent"`
+		Token string  `flag:"required,secret" env:"-"`
+		Count int
+	}
+	cmd := Command("TEST", RunFuncs[Conf]{}, cobra.Command{Use: "test"}, Conf{Log: LogConf{Level: "info"}})
+	want := []FieldInfo{
+		{
+			Field: "Log.Level", Type: reflect.TypeOf(""), Flag: "log-level", Shorthand: "l",
+			Env: "TEST_LOG_LEVEL", Default: "info", Usage: "log level", Persistent: true,
+		},
+		{
+			Field: "Token", Type: reflect.TypeOf(""), Flag: "token", Required: true, Secret: true,
+		},
+		{
+			Field: "Count", Type: reflect.TypeOf(0), Flag: "count", Env: "TEST_COUNT", Default: "0",
+		},
+	}
+	if got := Fields(cmd); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected fields:\nwant %+v\ngot  %+v", want, got)
+	}
+	if got := Fields(&cobra.Command{}); got != nil {
+		t.Errorf("expected no fields for plain command, got %+v", got)
+	}
+}
+
+func TestWalk(t *testing.T) {
+	type Conf struct {
+		Name string
+	}
+	root := Command("TEST", RunFuncs[Conf]{}, cobra.Command{Use: "root"}, Conf{})
+	foo := Command("TEST_FOO", RunFuncs[Conf]{}, cobra.Command{Use: "foo"}, Conf{})
+	foo.AddCommand(Command("TEST_FOO_BAZ", RunFuncs[Conf]{}, cobra.Command{Use: "baz"}, Conf{}))
+	root.AddCommand(foo, Command("TEST_BAR", RunFuncs[Conf]{}, cobra.Command{Use: "bar"}, Conf{}))
+
+	var visited []string
+	err := Walk(root, func(cmd *cobra.Command, fields []FieldInfo) error {
+		visited = append(visited, cmd.CommandPath()+" "+fields[0].Env)
+		if cmd.Name() == "baz" {
+			return errStop
+		}
+		return nil
+	})
+	if err != errStop {
+		t.Errorf("expected error of callback to be returned, got %v", err)
+	}
+	want := []string{"root TEST_NAME", "root bar TEST_BAR_NAME", "root foo TEST_FOO_NAME", "root foo baz TEST_FOO_BAZ_NAME"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("unexpected visit order:\nwant %q\ngot  %q", want, visited)
+	}
+}
+
+var errStop = errors.New("stop")
@@ -0,0 +1,72 @@
+package nicecmd
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// WithFS makes the command read files from fsys instead of the file system of the operating system:
+// configuration files, environment files, files referred to by environment variables, and the files
+// checked by the exists option. Paths are taken relative to the root of fsys, which is also the
+// working directory. It is meant for tests, e.g. with a testing/fstest.MapFS:
+//
+//	nicecmd.WithFS(fstest.MapFS{"etc/foo/config.json": {Data: []byte(`{"port": 8080}`)}})
+func WithFS(fsys fs.FS) Option {
+	return func(o *options) {
+		o.files = fileSystem{fsys: fsys}
+	}
+}
+
+// fileSystem reads files from the operating system, or from fsys if set.
+type fileSystem struct {
+	fsys fs.FS
+}
+
+// name converts an operating system path to a path within fsys.
+func (f fileSystem) name(p string) string {
+	p = filepath.ToSlash(strings.TrimPrefix(p, filepath.VolumeName(p)))
+	if p = strings.TrimPrefix(path.Clean("/"+p), "/"); p == "" {
+		return "."
+	}
+	return p
+}
+
+func (f fileSystem) readFile(p string) ([]byte, error) {
+	if f.fsys == nil {
+		return os.ReadFile(p)
+	}
+	return fs.ReadFile(f.fsys, f.name(p))
+}
+
+func (f fileSystem) stat(p string) (fs.FileInfo, error) {
+	if f.fsys == nil {
+		return os.Stat(p)
+	}
+	return fs.Stat(f.fsys, f.name(p))
+}
+
+func (f fileSystem) open(p string) (fs.File, error) {
+	if f.fsys == nil {
+		return os.Open(p)
+	}
+	return f.fsys.Open(f.name(p))
+}
+
+// glob is like filepath.Glob. Within fsys, the matches are paths within fsys.
+func (f fileSystem) glob(pattern string) ([]string, error) {
+	if f.fsys == nil {
+		return filepath.Glob(pattern)
+	}
+	return fs.Glob(f.fsys, f.name(pattern))
+}
+
+// getwd returns the working directory, which is the root of fsys.
+func (f fileSystem) getwd() (string, error) {
+	if f.fsys == nil {
+		return os.Getwd()
+	}
+	return string(filepath.Separator), nil
+}
@@ -0,0 +1,95 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileSystem_Name(t *testing.T) {
+	var files fileSystem
+	for in, want := range map[string]string{
+		"":                         ".",
+		"/":                        ".",
+		".":                        ".",
+		"config.json":              "config.json",
+		"./conf.d/a.env":           "conf.d/a.env",
+		"/etc/foo/config.json":     "etc/foo/config.json",
+		"/etc/foo/../bar/x.json":   "etc/bar/x.json",
+		"../../outside":            "outside",
+		"conf.d/*.env":             "conf.d/*.env",
+		filepath.Join("a", "b.js"): "a/b.js",
+	} {
+		if got := files.name(in); got != want {
+			t.Errorf("%q: expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestWithFS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("configuration discovery does not look in /etc on Windows")
+	}
+	fsys := fstest.MapFS{
+		"etc/test/config.json": {Data: []byte(`{"port": 8080, "name": "etc"}`)},
+		"app.json":             {Data: []byte(`{"name": "flag"}`)},
+		".env":                 {Data: []byte("TEST_USER=dotenv\nTEST_LEVEL=dotenv\n")},
+		"conf.d/10-level.env":  {Data: []byte("TEST_LEVEL=debug\n")},
+		"run/secrets/token":    {Data: []byte("hunter2\n")},
+		"run/secrets/key":      {Data: []byte("s3cr3t\n")},
+		"var/data/db.sqlite":   {},
+	}
+	type Conf struct {
+		Port  int
+		Name  string
+		User  string
+		Level string
+		Token string `flag:"secret"`
+		Key   string `flag:"secret"`
+		DB    string `flag:"exists" complete:"file"`
+	}
+	var warnings []string
+	WarningHandler = func(cmd *cobra.Command, msg string) {
+		warnings = append(warnings, msg)
+	}
+	defer func() { WarningHandler = nil }()
+	dotEnvWarned.Delete("/.env")
+	var got Conf
+	cmd, err := TryCommand("TEST", Run(func(cfg Conf, cmd *cobra.Command, args []string) error {
+		got = cfg
+		return nil
+	}), cobra.Command{Use: "test"}, Conf{}, WithFS(fsys), WithConfigDiscovery("test"), WithConfigFlag("config"),
+		WithDotEnvDiscovery(), WithEnvFile("conf.d/*.env"), WithEnvFileSuffix("_FILE"), WithEnviron([]string{
+			"TEST_TOKEN_FILE=/run/secrets/token",
+			"TEST_KEY=file:///run/secrets/key",
+		}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	cmd.SetArgs([]string{"--config", "/app.json", "--db", "/var/data/db.sqlite"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	want := Conf{Port: 8080, Name: "flag", User: "dotenv", Level: "debug", Token: "hunter2", Key: "s3cr3t", DB: "/var/data/db.sqlite"}
+	if got != want {
+		t.Errorf("expected all files to be read from fsys:\nwant %+v\ngot  %+v", want, got)
+	}
+	if len(warnings) != 1 || warnings[0] != "environment variables loaded from /.env" {
+		t.Errorf("expected warning for the discovered .env file, got %q", warnings)
+	}
+
+	cmd.SetArgs([]string{"--db", "/var/data/missing.sqlite"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "open var/data/missing.sqlite: file does not exist") {
+		t.Errorf("expected missing file within fsys, got %v", err)
+	}
+	cmd.SetArgs([]string{"--db", "/var/data"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "is a directory") {
+		t.Errorf("expected error for directory within fsys, got %v", err)
+	}
+}
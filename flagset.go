@@ -0,0 +1,74 @@
+package nicecmd
+
+import (
+	"flag"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// BindFlagSet is like TryBindConfig, but adds the flags to fs, for programs that do not use Cobra.
+// Environment variables are applied just the same. As there is no command to enforce them, the
+// required option only marks flags via Cobra's annotation, and parse errors of secret flags are
+// not redacted.
+func BindFlagSet(envPrefix string, fs *pflag.FlagSet, cfg any, opts ...Option) error {
+	flags, err := bindDetached(envPrefix, cfg, opts)
+	if err != nil && flags == nil {
+		return err
+	}
+	for _, f := range flags {
+		if fs.Lookup(f.Name) != nil {
+			return &BindError{Msg: fmt.Sprintf("flag %q is already defined", f.Name)}
+		}
+		if f.Shorthand != "" && fs.ShorthandLookup(f.Shorthand) != nil {
+			return &BindError{Msg: fmt.Sprintf("shorthand %q for %q is already defined", f.Shorthand, f.Name)}
+		}
+	}
+	for _, f := range flags {
+		fs.AddFlag(f)
+	}
+	return err
+}
+
+// BindGoFlagSet is like BindFlagSet, but adds the flags to a flag.FlagSet of the standard library.
+// Shorthands are added as additional flags with the same value.
+func BindGoFlagSet(envPrefix string, fs *flag.FlagSet, cfg any, opts ...Option) error {
+	flags, err := bindDetached(envPrefix, cfg, opts)
+	if err != nil && flags == nil {
+		return err
+	}
+	for _, f := range flags {
+		for _, name := range []string{f.Name, f.Shorthand} {
+			if name != "" && fs.Lookup(name) != nil {
+				return &BindError{Msg: fmt.Sprintf("flag %q is already defined", name)}
+			}
+		}
+	}
+	for _, f := range flags {
+		// pflag's values implement flag.Value, including IsBoolFlag for booleans
+		fs.Var(f.Value, f.Name, f.Usage)
+		if f.Shorthand != "" {
+			fs.Var(f.Value, f.Shorthand, f.Usage)
+		}
+	}
+	return err
+}
+
+// bindDetached binds cfg to a throwaway command and returns its flags, along with joined
+// *EnvError if environment variables are invalid. The flags are nil if cfg cannot be bound.
+func bindDetached(envPrefix string, cfg any, opts []Option) ([]*pflag.Flag, error) {
+	cmd := &cobra.Command{}
+	envErrs, err := bindConfig(envPrefix, cmd, cfg, newOptions(opts), envSource{})
+	if err != nil {
+		return nil, err
+	}
+	var flags []*pflag.Flag
+	add := func(f *pflag.Flag) {
+		if !isBindingFlag(f) {
+			flags = append(flags, f)
+		}
+	}
+	cmd.Flags().VisitAll(add)
+	cmd.PersistentFlags().VisitAll(add)
+	return flags, joinEnvErrors(envErrs)
+}
@@ -0,0 +1,58 @@
+package nicecmd
+
+import (
+	"errors"
+	"flag"
+	"github.com/spf13/pflag"
+	"io"
+	"testing"
+)
+
+type flagSetConf struct {
+	Name    string `param:"name,n"`
+	Verbose bool   `flag:"persistent"`
+	Port    int
+}
+
+func TestBindFlagSet(t *testing.T) {
+	t.Setenv("TEST_PORT", "8080")
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	var cfg flagSetConf
+	if err := BindFlagSet("TEST", fs, &cfg); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := fs.Parse([]string{"-n", "foo", "--verbose"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg != (flagSetConf{Name: "foo", Verbose: true, Port: 8080}) {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	var bindErr *BindError
+	if err := BindFlagSet("TEST", fs, &flagSetConf{}); !errors.As(err, &bindErr) {
+		t.Errorf("expected BindError for duplicate flags, got %v", err)
+	}
+
+	t.Setenv("TEST_PORT", "x")
+	var envErr *EnvError
+	fs = pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := BindFlagSet("TEST", fs, &flagSetConf{}); !errors.As(err, &envErr) || fs.Lookup("port") == nil {
+		t.Errorf("expected EnvError along with flags, got %v", err)
+	}
+}
+
+func TestBindGoFlagSet(t *testing.T) {
+	t.Setenv("TEST_PORT", "8080")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	var cfg flagSetConf
+	if err := BindGoFlagSet("TEST", fs, &cfg); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := fs.Parse([]string{"-n", "foo", "-verbose"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if cfg != (flagSetConf{Name: "foo", Verbose: true, Port: 8080}) {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
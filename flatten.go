@@ -0,0 +1,102 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"reflect"
+)
+
+// Flatten returns the values of cfg keyed by flag name, formatted exactly like the flags that
+// BindConfig would create for them. cfg is a configuration struct or a pointer to one. Note that
+// values of secret fields are included as well, use FlattenRedacted for output meant for humans.
+//
+// Like BindConfig, Flatten panics if cfg cannot be bound.
+func Flatten(cfg any) map[string]string {
+	return flatten(cfg, false)
+}
+
+// FlattenRedacted is like Flatten, but replaces the values of secret fields with "<redacted>", so
+// that the result can safely be logged or printed.
+func FlattenRedacted(cfg any) map[string]string {
+	return flatten(cfg, true)
+}
+
+func flatten(cfg any, redact bool) map[string]string {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		panic("cfg must be a struct or struct pointer")
+	}
+	cmd, _ := bindCopy(v)
+	values := make(map[string]string)
+	add := func(flag *pflag.Flag) {
+		if isBindingFlag(flag) {
+			return
+		}
+		if redact && isSecret(flag) {
+			values[flag.Name] = redacted
+		} else {
+			values[flag.Name] = flag.Value.String()
+		}
+	}
+	cmd.Flags().VisitAll(add)
+	cmd.PersistentFlags().VisitAll(add)
+	return values
+}
+
+// FieldChange describes a field that differs between two configurations, see Diff.
+type FieldChange struct {
+	Field  string // Go path of the field, e.g. "Log.Level"
+	Flag   string // flag name without dashes
+	Old    string // old value, formatted like the flag's value
+	New    string // new value, formatted like the flag's value
+	Secret bool   // whether Old and New must not be shown
+}
+
+// Diff returns the fields whose formatted values differ between old and new, in declaration order.
+// It panics if T cannot be bound, like BindConfig.
+func Diff[T any](old, new *T) []FieldChange {
+	oldCmd, fields := bindCopy(reflect.ValueOf(old).Elem())
+	newCmd, _ := bindCopy(reflect.ValueOf(new).Elem())
+	var changes []FieldChange
+	for _, field := range fields {
+		oldVal := boundFlag(oldCmd, field).Value.String()
+		newVal := boundFlag(newCmd, field).Value.String()
+		if oldVal != newVal {
+			changes = append(changes, FieldChange{
+				Field:  field.Field,
+				Flag:   field.Flag,
+				Old:    oldVal,
+				New:    newVal,
+				Secret: field.Secret,
+			})
+		}
+	}
+	return changes
+}
+
+// bindCopy binds a copy of the configuration struct v to a throwaway command, so that the
+// formatted values of its fields can be inspected without modifying v. Tag defaults are not
+// applied, as they would replace the values of v. It panics if v cannot be bound.
+func bindCopy(v reflect.Value) (*cobra.Command, []FieldInfo) {
+	copied := reflect.New(v.Type())
+	copied.Elem().Set(v)
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	if _, err := bindConfig("", cmd, copied.Interface(), options{keepValues: true}, mapEnv(nil)); err != nil {
+		panic(err.Error())
+	}
+	return cmd, lookupBinding(cmd).fields
+}
+
+// boundFlag returns the flag of cmd that field was bound to.
+func boundFlag(cmd *cobra.Command, field FieldInfo) *pflag.Flag {
+	if field.Persistent {
+		return cmd.PersistentFlags().Lookup(field.Flag)
+	}
+	return cmd.Flags().Lookup(field.Flag)
+}
@@ -0,0 +1,91 @@
+package nicecmd
+
+import (
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFlatten(t *testing.T) {
+	type LogConf struct {
+		Level string
+	}
+	type Conf struct {
+		Log     LogConf `flag:"persistent"`
+		Tags    []string
+		Timeout time.Duration
+		Token   string `flag:"secret"`
+	}
+	cfg := Conf{Log: LogConf{Level: "info"}, Tags: []string{"a", "b"}, Timeout: time.Minute, Token: "t0ps3cret"}
+	want := map[string]string{
+		"log-level": "info",
+		"tags":      "[a,b]",
+		"timeout":   "1m0s",
+		"token":     "t0ps3cret",
+	}
+	if got := Flatten(cfg); !maps.Equal(got, want) {
+		t.Errorf("unexpected values:\nwant %q\ngot  %q", want, got)
+	}
+	if got := Flatten(&cfg); !maps.Equal(got, want) {
+		t.Errorf("unexpected values for pointer:\nwant %q\ngot  %q", want, got)
+	}
+	want["token"] = "<redacted>"
+	if got := FlattenRedacted(&cfg); !maps.Equal(got, want) {
+		t.Errorf("unexpected redacted values:\nwant %q\ngot  %q", want, got)
+	}
+	if cfg.Tags[0] != "a" || cfg.Log.Level != "info" {
+		t.Errorf("expected cfg to be unmodified, got %+v", cfg)
+	}
+}
+
+func TestFlatten_TagDefaults(t *testing.T) {
+	type Conf struct {
+		Port  int    `default:"8080"`
+		Level string `envDefault:"info"`
+	}
+	want := map[string]string{"port": "9090", "level": "debug"}
+	if got := Flatten(Conf{Port: 9090, Level: "debug"}); !maps.Equal(got, want) {
+		t.Errorf("expected the values of the struct rather than the tag defaults:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	type LogConf struct {
+		Level string
+	}
+	type Conf struct {
+		Log   LogConf `flag:"persistent"`
+		Tags  []string
+		Token string `flag:"secret"`
+		Port  int
+	}
+	old := Conf{Log: LogConf{Level: "info"}, Tags: []string{"a"}, Token: "foo", Port: 80}
+	new := Conf{Log: LogConf{Level: "debug"}, Tags: []string{"a"}, Token: "bar", Port: 80}
+	want := []FieldChange{
+		{Field: "Log.Level", Flag: "log-level", Old: "info", New: "debug"},
+		{Field: "Token", Flag: "token", Old: "foo", New: "bar", Secret: true},
+	}
+	if got := Diff(&old, &new); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes:\nwant %+v\ngot  %+v", want, got)
+	}
+	if got := Diff(&old, &old); got != nil {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
+
+func TestDiff_TagDefaults(t *testing.T) {
+	type Conf struct {
+		Port  int    `default:"8080"`
+		Level string `envDefault:"info"`
+	}
+	old := Conf{Port: 8080, Level: "info"}
+	new := Conf{Port: 9090, Level: "debug"}
+	want := []FieldChange{
+		{Field: "Port", Flag: "port", Old: "8080", New: "9090"},
+		{Field: "Level", Flag: "level", Old: "info", New: "debug"},
+	}
+	if got := Diff(&old, &new); !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected changes:\nwant %+v\ngot  %+v", want, got)
+	}
+}
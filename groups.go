@@ -0,0 +1,92 @@
+package nicecmd
+
+import (
+	"github.com/spf13/pflag"
+	"slices"
+	"strings"
+)
+
+const (
+	// groupMutex allows at most one flag of a group to be set, see cobra.MarkFlagsMutuallyExclusive.
+	groupMutex = "mutex"
+
+	// groupTogether requires all flags of a group if any is set, see
+	// cobra.MarkFlagsRequiredTogether. The usage of each flag names the others.
+	groupTogether = "together"
+
+	// groupRequired requires at least one flag of a group, see cobra.MarkFlagsOneRequired.
+	groupRequired = "required"
+)
+
+// flagGroup collects the flags that share a name in their group tags.
+type flagGroup struct {
+	name  string
+	field string   // first field of the group, for errors
+	kinds []string // e.g. groupMutex
+	flags []*pflag.Flag
+}
+
+// addToGroups adds param to the groups of its group tag, e.g. `group:"auth,mutex"`. Fields may
+// belong to several groups, separated by "|". All fields of a group must give the same kinds.
+func (b *binder) addToGroups(fieldName string, tags fieldTags, param *pflag.Flag) error {
+	for _, spec := range tags.groups {
+		name, kinds, _ := strings.Cut(spec, ",")
+		if name == "" || kinds == "" {
+			return b.errorf(fieldName, `expected group:"name,kind" for %q, got %q`, tags.name, spec)
+		}
+		group := &flagGroup{name: name, field: fieldName, kinds: strings.Split(kinds, ",")}
+		for _, kind := range group.kinds {
+			switch kind {
+			case groupMutex, groupTogether, groupRequired:
+			default:
+				return b.errorf(fieldName, "unknown kind %q of group %q for %q", kind, name, tags.name)
+			}
+		}
+		if i := slices.IndexFunc(b.groups, func(g *flagGroup) bool { return g.name == name }); i != -1 {
+			if !slices.Equal(b.groups[i].kinds, group.kinds) {
+				return b.errorf(fieldName, "group %q for %q is %q, but %q for %s", name, tags.name,
+					kinds, strings.Join(b.groups[i].kinds, ","), b.groups[i].field)
+			}
+			group = b.groups[i]
+		} else {
+			b.groups = append(b.groups, group)
+		}
+		group.flags = append(group.flags, param)
+	}
+	return nil
+}
+
+// markGroups marks the flags of each group on the command, so that Cobra enforces the group.
+// Environment variables count like flags, as they mark their flags as changed.
+func (b *binder) markGroups() error {
+	for _, group := range b.groups {
+		if len(group.flags) < 2 {
+			return b.errorf(group.field, "group %q must have at least two flags", group.name)
+		}
+		names := make([]string, len(group.flags))
+		for i, flag := range group.flags {
+			names[i] = flag.Name
+		}
+		for _, kind := range group.kinds {
+			switch kind {
+			case groupMutex:
+				b.cmd.MarkFlagsMutuallyExclusive(names...)
+			case groupRequired:
+				b.cmd.MarkFlagsOneRequired(names...)
+			case groupTogether:
+				b.cmd.MarkFlagsRequiredTogether(names...)
+				for _, flag := range group.flags {
+					var others []string
+					for _, name := range names {
+						if name != flag.Name {
+							others = append(others, "--"+name)
+						}
+					}
+					setAnnotation(flag, annotationTogether, others...)
+					decorateUsage(flag)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"io"
+	"strings"
+	"testing"
+)
+
+type authConf struct {
+	Token     string `group:"auth,mutex"`
+	TokenFile string `group:"auth,mutex"`
+	Basic     struct {
+		User string `group:"auth,mutex"`
+	}
+}
+
+func executeGroups[T any](t *testing.T, environ []string, args ...string) error {
+	t.Helper()
+	var cfg T
+	cmd, err := TryCommand("TEST", RunFuncs[T]{}, cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}, cfg,
+		WithEnviron(environ))
+	if err != nil {
+		return err
+	}
+	cmd.SetArgs(args)
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	return cmd.Execute()
+}
+
+func TestGroups_Mutex(t *testing.T) {
+	if err := executeGroups[authConf](t, nil, "--token", "x"); err != nil {
+		t.Errorf("expected a single flag of the group to be accepted, got %v", err)
+	}
+	err := executeGroups[authConf](t, nil, "--token", "x", "--basic-user", "bob")
+	if err == nil || !strings.Contains(err.Error(), "[basic-user token] were all set") {
+		t.Errorf("expected error for flags of a nested struct in the same group, got %v", err)
+	}
+	err = executeGroups[authConf](t, []string{"TEST_TOKEN_FILE=/run/token"}, "--token", "x")
+	if err == nil || !strings.Contains(err.Error(), "none of the others") {
+		t.Errorf("expected environment variables to count like flags, got %v", err)
+	}
+}
+
+func TestGroups_Invalid(t *testing.T) {
+	var bindErr *BindError
+	for name, conf := range map[string]any{
+		"no kind": &struct {
+			A, B string `group:"auth"`
+		}{},
+		"unknown kind": &struct {
+			A, B string `group:"auth,xor"`
+		}{},
+		"different kinds": &struct {
+			A string `group:"auth,mutex"`
+			B string `group:"auth,mutex,mutex"`
+		}{},
+		"single flag": &struct {
+			A string `group:"auth,mutex"`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("%s: expected *BindError, got %v", name, err)
+		}
+	}
+}
+
+type tlsConf struct {
+	TLS struct {
+		Cert string `group:"tls,together"`
+		Key  string `group:"tls,together"`
+		CA   string `group:"tls,together"`
+	}
+}
+
+func TestGroups_Together(t *testing.T) {
+	if err := executeGroups[tlsConf](t, nil); err != nil {
+		t.Errorf("expected group to be optional, got %v", err)
+	}
+	err := executeGroups[tlsConf](t, []string{"TEST_TLS_KEY=key.pem"}, "--tls-cert", "cert.pem")
+	if err == nil || !strings.Contains(err.Error(), "missing [tls-ca]") {
+		t.Errorf("expected error naming the missing flag, got %v", err)
+	}
+	if err := executeGroups[tlsConf](t, []string{"TEST_TLS_KEY=key.pem", "TEST_TLS_CA=ca.pem"}, "--tls-cert", "cert.pem"); err != nil {
+		t.Errorf("expected environment variables to count like flags, got %v", err)
+	}
+
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &tlsConf{}, WithEnviron(nil)); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if usage := cmd.Flags().Lookup("tls-key").Usage; usage != "(with --tls-cert, --tls-ca) (env TEST_TLS_KEY)" {
+		t.Errorf("expected usage to name the other flags, got %q", usage)
+	}
+}
+
+func TestGroups_Required(t *testing.T) {
+	type tokenConf struct {
+		Token     string `group:"auth,mutex,required"`
+		TokenFile string `group:"auth,mutex,required"`
+	}
+	err := executeGroups[tokenConf](t, nil)
+	if err == nil || !strings.Contains(err.Error(), "at least one of the flags in the group [token token-file] is required") {
+		t.Errorf("expected error for group without any flag, got %v", err)
+	}
+	if err := executeGroups[tokenConf](t, []string{"TEST_TOKEN_FILE=/run/token"}); err != nil {
+		t.Errorf("expected environment variable to satisfy the group, got %v", err)
+	}
+	if err := executeGroups[tokenConf](t, nil, "--token", "x", "--token-file", "y"); err == nil {
+		t.Error("expected group to remain mutually exclusive")
+	}
+}
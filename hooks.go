@@ -0,0 +1,34 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"time"
+)
+
+// HookKind identifies one of the functions of RunFuncs.
+type HookKind string
+
+const (
+	HookPersistentPreRun  HookKind = "PersistentPreRun"
+	HookPreRun            HookKind = "PreRun"
+	HookRun               HookKind = "Run"
+	HookPostRun           HookKind = "PostRun"
+	HookPersistentPostRun HookKind = "PersistentPostRun"
+)
+
+// HookEvent describes a completed invocation of a hook.
+type HookEvent struct {
+	Command  *cobra.Command // command that is being executed
+	Hook     HookKind
+	Duration time.Duration
+	Err      error // error returned by the hook
+}
+
+// WithHookObserver calls observe after each invocation of the command's hooks, e.g. to collect
+// telemetry or to check the order of hooks in a test. Note that with Cobra's traverse run hooks,
+// the persistent hooks of a parent command report the executed sub-command.
+func WithHookObserver(observe func(ev HookEvent)) Option {
+	return func(o *options) {
+		o.hookObserver = observe
+	}
+}
@@ -0,0 +1,48 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"testing"
+)
+
+func TestWithHookObserver(t *testing.T) {
+	errRun := errors.New("run failed")
+	var events []HookEvent
+	observe := func(ev HookEvent) {
+		events = append(events, ev)
+	}
+	noop := func(cfg TrivialConf, cmd *cobra.Command, args []string) error { return nil }
+	cmd := Command("TEST", RunFuncs[TrivialConf]{
+		PreRun: noop,
+		Run: func(cfg TrivialConf, cmd *cobra.Command, args []string) error {
+			return errRun
+		},
+		PostRun: noop,
+	}, cobra.Command{Use: "test", SilenceErrors: true, SilenceUsage: true}, TrivialConf{}, WithHookObserver(observe))
+	cmd.SetArgs([]string{})
+	if err := cmd.Execute(); err != errRun {
+		t.Fatalf("expected run error, got %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected two events, got %+v", events)
+	}
+	if ev := events[0]; ev.Hook != HookPreRun || ev.Command != cmd || ev.Err != nil {
+		t.Errorf("unexpected first event: %+v", ev)
+	}
+	if ev := events[1]; ev.Hook != HookRun || ev.Err != errRun || ev.Duration < 0 {
+		t.Errorf("unexpected second event: %+v", ev)
+	}
+
+	// The observer must survive cloning
+	events = nil
+	clone, err := Clone(cmd)
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	clone.SetArgs([]string{})
+	_ = clone.Execute()
+	if len(events) != 2 || events[0].Command != clone {
+		t.Errorf("expected events for clone, got %+v", events)
+	}
+}
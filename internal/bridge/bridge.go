@@ -0,0 +1,21 @@
+// Package bridge gives the helper packages of nicecmd access to its internals, without making
+// them part of its public API. Package nicecmd sets these functions during initialization.
+package bridge
+
+import (
+	"github.com/spf13/cobra"
+	"io"
+)
+
+var (
+	// Clone rebuilds the command tree at root with fresh configuration and flag state, binding
+	// environment variables from env instead of the process environment.
+	Clone func(root *cobra.Command, env map[string]string, stdout, stderr io.Writer) (*cobra.Command, error)
+
+	// Config returns the current configuration bound to cmd.
+	Config func(cmd *cobra.Command) (any, bool)
+
+	// PersistentHooks returns the persistent hooks of cmd itself. For commands created by nicecmd,
+	// these are not the hooks in the fields of cmd, which run the hooks of all parents as well.
+	PersistentHooks func(cmd *cobra.Command) (pre, post *func(cmd *cobra.Command, args []string) error)
+)
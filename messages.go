@@ -0,0 +1,70 @@
+package nicecmd
+
+// Messages are the texts that nicecmd shows to users of your CLI, as opposed to programming errors
+// such as BindError, which are always in English. Each text is a format string for fmt.Sprintf,
+// the comments list its arguments.
+type Messages struct {
+	Required            string // usage suffix of required flags
+	Together            string // usage suffix of flags required together: other flags joined by ", "
+	Negation            string // usage of the --no-<name> flag of negatable flags: flag name
+	Choices             string // usage suffix of flags with the choices tag: choices joined by ", "
+	InvalidChoice       string // error for a value that is not among the choices: choices joined by ", "
+	TooSmall            string // error for a value below the min tag: minimum
+	TooLarge            string // error for a value above the max tag: maximum
+	NoMatch             string // error for a value that does not match the pattern tag: pattern
+	InvalidScheme       string // error for a URL whose scheme is not in the schemes tag: schemes joined by ", "
+	NotDir              string // error for a path that must be a directory: path
+	IsDir               string // error for a path that must be a file: path
+	Env                 string // usage suffix of flags bound to an environment variable: variable name
+	EnvSet              string // usage suffix if that variable is set: variable name, value
+	EnvSetSecret        string // usage suffix if the variable of a secret flag is set: variable name
+	InvalidEnv          string // error for a variable with an invalid value: variable name, error
+	InvalidSecretEnv    string // error for a secret variable with an invalid value: variable name
+	InvalidSecretFlag   string // error for a secret flag with an invalid value: flag name
+	InvalidArg          string // error for a positional argument with an invalid value: value, name, error
+	InvalidSecretArg    string // error for a secret positional argument with an invalid value: name
+	MissingArg          string // error for a required positional argument that is missing: name
+	Warning             string // prefix of warnings emitted via Warn
+	EnvDeprecated       string // warning about a deprecated variable: variable name, replacement
+	InvalidEnvFile      string // error for an environment file that cannot be read or parsed: path, error
+	DotEnvLoaded        string // warning about a discovered environment file: path
+	EnvPrefix           string // hint about a mistyped prefix: expected prefix, count of variables, similar prefix
+	ConfigUsage         string // usage of the flag added by WithConfigFlag
+	InvalidConfigFile   string // error for a configuration file that cannot be read: path, error
+	InvalidConfig       string // error for an invalid value in a configuration file: path, key, error
+	InvalidSecretConfig string // error for an invalid value of a secret flag in a configuration file: path, key
+}
+
+// DefaultMessages is used for all texts shown by nicecmd. Change it before constructing commands to
+// localize the output. Cobra's own texts can be localized via its templates and SetErrPrefix.
+var DefaultMessages = Messages{
+	Required:            "required",
+	Together:            "with %s",
+	Negation:            "sets --%s to false",
+	Choices:             "one of %s",
+	InvalidChoice:       "must be one of %s",
+	TooSmall:            "must be at least %s",
+	TooLarge:            "must be at most %s",
+	NoMatch:             "must match %s",
+	InvalidScheme:       "scheme must be one of %s",
+	NotDir:              "%s is not a directory",
+	IsDir:               "%s is a directory",
+	Env:                 "env %s",
+	EnvSet:              "env %s=%q",
+	EnvSetSecret:        "env %s=<redacted>",
+	InvalidEnv:          "environment variable %s: %s",
+	InvalidSecretEnv:    "environment variable %s: invalid value <redacted>",
+	InvalidSecretFlag:   "invalid argument <redacted> for %q flag",
+	InvalidArg:          "invalid argument %q for <%s>: %s",
+	InvalidSecretArg:    "invalid argument <redacted> for <%s>",
+	MissingArg:          "missing argument <%s>",
+	Warning:             "Warning:",
+	EnvDeprecated:       "environment variable %s is deprecated, use %s instead",
+	InvalidEnvFile:      "environment file %s: %s",
+	DotEnvLoaded:        "environment variables loaded from %s",
+	EnvPrefix:           "no environment variable with prefix %[1]s is set, but variables with the similar prefix %[3]s are (%[2]d)",
+	ConfigUsage:         "configuration file",
+	InvalidConfigFile:   "configuration file %s: %s",
+	InvalidConfig:       "configuration file %s: key %s: %s",
+	InvalidSecretConfig: "configuration file %s: key %s: invalid value <redacted>",
+}
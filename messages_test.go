@@ -0,0 +1,50 @@
+package nicecmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMessages_Localized(t *testing.T) {
+	defer func(m Messages) { DefaultMessages = m }(DefaultMessages)
+	DefaultMessages = Messages{
+		Required:   "erforderlich",
+		Env:        "Umgebung %s",
+		EnvSet:     "Umgebung %s=%q",
+		InvalidEnv: "Umgebungsvariable %s: %s",
+		Warning:    "Warnung:",
+	}
+
+	if err := os.Setenv("NICECMD_L10N_BAD", "x"); err != nil {
+		t.Errorf("setenv: %v", err)
+		return
+	}
+	defer func() { _ = os.Unsetenv("NICECMD_L10N_BAD") }()
+
+	var conf struct {
+		Name string `flag:"required"`
+		Bad  int
+	}
+	cmd := &cobra.Command{}
+	cmd.SetErrPrefix("Fehler:")
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetErr(buf)
+	BindConfig("NICECMD_L10N", cmd, &conf)
+	Warn(cmd, "Achtung")
+
+	usage := cmd.Flags().FlagUsages()
+	for _, want := range []string{"(erforderlich) (Umgebung NICECMD_L10N_NAME)", `Umgebung NICECMD_L10N_BAD="x"`} {
+		if !strings.Contains(usage, want) {
+			t.Errorf("expected usage to contain %q, got:\n%s", want, usage)
+		}
+	}
+	for _, want := range []string{"Fehler: Umgebungsvariable NICECMD_L10N_BAD: ", "Warnung: Achtung"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package nicecmdtest
+
+import (
+	"flag"
+	"github.com/mologie/nicecmd/internal/bridge"
+	"github.com/spf13/cobra"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update-golden", false, "update golden files of nicecmdtest.Golden")
+
+var ansiEscape = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// StripANSI removes terminal color sequences, as used by nicecmd in usage strings.
+func StripANSI(s string) string {
+	return ansiEscape.ReplaceAllString(s, "")
+}
+
+// Help returns the help output of the command selected by Args, without colors.
+func Help(t testing.TB, root *cobra.Command, opts ...Option) string {
+	t.Helper()
+	res := Execute[any](t, root, append(opts, Args("--help"))...)
+	if res.Err != nil {
+		t.Errorf("nicecmdtest: help: %v", res.Err)
+	}
+	return StripANSI(res.Stdout)
+}
+
+// Usage returns the usage of the command selected by Args, without colors.
+func Usage(t testing.TB, root *cobra.Command, opts ...Option) string {
+	t.Helper()
+	restoreGlobals(t)
+	e := newExecution(opts)
+	clone, err := bridge.Clone(root, e.env, nil, nil)
+	if err != nil {
+		t.Errorf("nicecmdtest: usage: %v", err)
+		return ""
+	}
+	cmd, _, err := clone.Find(e.args)
+	if err != nil {
+		t.Errorf("nicecmdtest: usage: %v", err)
+		return ""
+	}
+	return StripANSI(cmd.UsageString())
+}
+
+// Golden compares got with the file testdata/<name>.golden. Run the test with -update-golden to
+// write got to the file instead, e.g. after reviewing changes in help output.
+func Golden(t testing.TB, name string, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("nicecmdtest: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("nicecmdtest: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("nicecmdtest: %v (run with -update-golden to create it)", err)
+	}
+	if string(want) != got {
+		t.Errorf("nicecmdtest: output does not match %s (run with -update-golden to update it)\n"+
+			"--- want ---\n%s\n--- got ---\n%s", path, want, got)
+	}
+}
@@ -0,0 +1,22 @@
+package nicecmdtest_test
+
+import (
+	"github.com/mologie/nicecmd/nicecmdtest"
+	"testing"
+)
+
+func TestHelp_Golden(t *testing.T) {
+	help := nicecmdtest.Help(t, newRootCmd(), nicecmdtest.Args("greet"),
+		nicecmdtest.Env(map[string]string{"TEST_GREET_NAME": "Gopher"}))
+	nicecmdtest.Golden(t, "greet_help", help)
+}
+
+func TestUsage_Golden(t *testing.T) {
+	nicecmdtest.Golden(t, "root_usage", nicecmdtest.Usage(t, newRootCmd()))
+}
+
+func TestStripANSI(t *testing.T) {
+	if got := nicecmdtest.StripANSI("(\033[32menv FOO=\"bar\"\033[0m)"); got != `(env FOO="bar")` {
+		t.Errorf("unexpected result: %q", got)
+	}
+}
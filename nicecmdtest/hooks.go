@@ -0,0 +1,39 @@
+package nicecmdtest
+
+import (
+	"github.com/mologie/nicecmd/internal/bridge"
+	"github.com/spf13/cobra"
+)
+
+// HookCall records the invocation of a single hook during Execute.
+type HookCall struct {
+	Command string // path of the command that defines the hook, e.g. "root greet"
+	Hook    string // "PersistentPreRun", "PreRun", "Run", "PostRun" or "PersistentPostRun"
+	Err     error  // error returned by the hook
+}
+
+// recordHooks wraps all hooks of cmd and its sub-commands, so that their invocations are appended
+// to calls in the order in which Cobra runs them.
+func recordHooks(cmd *cobra.Command, calls *[]HookCall) {
+	record := func(name string, hook func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
+		if hook == nil {
+			return nil
+		}
+		path := cmd.CommandPath()
+		return func(c *cobra.Command, args []string) error {
+			err := hook(c, args)
+			*calls = append(*calls, HookCall{Command: path, Hook: name, Err: err})
+			return err
+		}
+	}
+	// nicecmd chains the persistent hooks of parents, so record those of the command itself
+	pre, post := bridge.PersistentHooks(cmd)
+	*pre = record("PersistentPreRun", *pre)
+	cmd.PreRunE = record("PreRun", cmd.PreRunE)
+	cmd.RunE = record("Run", cmd.RunE)
+	cmd.PostRunE = record("PostRun", cmd.PostRunE)
+	*post = record("PersistentPostRun", *post)
+	for _, sub := range cmd.Commands() {
+		recordHooks(sub, calls)
+	}
+}
@@ -0,0 +1,39 @@
+package nicecmdtest_test
+
+import (
+	"errors"
+	"github.com/mologie/nicecmd"
+	"github.com/mologie/nicecmd/nicecmdtest"
+	"github.com/spf13/cobra"
+	"reflect"
+	"testing"
+)
+
+func TestExecute_Hooks(t *testing.T) {
+	errRun := errors.New("run failed")
+	noop := func(cfg struct{}, cmd *cobra.Command, args []string) error { return nil }
+	root := nicecmd.Command("TEST", nicecmd.RunFuncs[struct{}]{
+		PersistentPreRun:  noop,
+		PersistentPostRun: noop,
+	}, cobra.Command{Use: "root"}, struct{}{})
+	root.AddCommand(nicecmd.Command("TEST_FAIL", nicecmd.RunFuncs[struct{}]{
+		PreRun: noop,
+		Run: func(cfg struct{}, cmd *cobra.Command, args []string) error {
+			return errRun
+		},
+		PostRun: noop,
+	}, cobra.Command{Use: "fail"}, struct{}{}))
+
+	res := nicecmdtest.Execute[struct{}](t, root, nicecmdtest.Args("fail"))
+	if res.Err != errRun {
+		t.Errorf("expected run error, got %v", res.Err)
+	}
+	want := []nicecmdtest.HookCall{
+		{Command: "root", Hook: "PersistentPreRun"},
+		{Command: "root fail", Hook: "PreRun"},
+		{Command: "root fail", Hook: "Run", Err: errRun},
+	}
+	if !reflect.DeepEqual(res.Hooks, want) {
+		t.Errorf("unexpected hooks:\nwant %+v\ngot  %+v", want, res.Hooks)
+	}
+}
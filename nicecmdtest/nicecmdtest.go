@@ -0,0 +1,101 @@
+// Package nicecmdtest executes nicecmd command trees in tests, isolated from the process
+// environment, from global state, and from other executions of the same tree.
+package nicecmdtest
+
+import (
+	"bytes"
+	"github.com/mologie/nicecmd"
+	"github.com/mologie/nicecmd/internal/bridge"
+	"github.com/spf13/cobra"
+	"maps"
+	"testing"
+)
+
+// Option configures an execution.
+type Option func(*execution)
+
+type execution struct {
+	args []string
+	env  map[string]string
+}
+
+// Args sets the command line arguments, without the program name.
+func Args(args ...string) Option {
+	return func(e *execution) {
+		e.args = append(e.args, args...)
+	}
+}
+
+// Env sets environment variables. The process environment is never visible to the command.
+func Env(env map[string]string) Option {
+	return func(e *execution) {
+		maps.Copy(e.env, env)
+	}
+}
+
+func newExecution(opts []Option) *execution {
+	e := &execution{args: []string{}, env: make(map[string]string)}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Result is the outcome of Execute.
+type Result[T any] struct {
+	Command *cobra.Command // command that was executed, nil if the tree could not be set up
+	Config  T              // configuration of the executed command after flags were parsed
+	Stdout  string
+	Stderr  string
+	Err     error
+	Hooks   []HookCall // hooks that ran, in order
+}
+
+// Execute runs a copy of the command tree root, which must have been created by nicecmd.Command,
+// and returns the configuration of the executed command along with its output and error.
+//
+// The tree is rebuilt for every call with fresh configuration and flag state, and environment
+// variables are only taken from Env. If any of them are invalid, then Err holds the
+// *nicecmd.EnvError and nothing is executed. nicecmd's and Cobra's global settings are restored
+// when the test completes.
+func Execute[T any](t testing.TB, root *cobra.Command, opts ...Option) (res Result[T]) {
+	t.Helper()
+	restoreGlobals(t)
+
+	e := newExecution(opts)
+
+	var stdout, stderr bytes.Buffer
+	defer func() {
+		res.Stdout, res.Stderr = stdout.String(), stderr.String()
+	}()
+
+	clone, err := bridge.Clone(root, e.env, &stdout, &stderr)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	recordHooks(clone, &res.Hooks)
+	clone.SetArgs(e.args)
+	res.Command, res.Err = clone.ExecuteC()
+	if res.Command != nil {
+		if cfg, ok := bridge.Config(res.Command); ok {
+			if res.Config, ok = cfg.(T); !ok {
+				t.Errorf("nicecmdtest: command %q has configuration type %T, not %T",
+					res.Command.CommandPath(), cfg, res.Config)
+			}
+		}
+	}
+	return res
+}
+
+// restoreGlobals restores the global settings of nicecmd and Cobra when the test completes.
+func restoreGlobals(t testing.TB) {
+	environment, debug := nicecmd.Environment, nicecmd.Debug
+	warningHandler, messages := nicecmd.WarningHandler, nicecmd.DefaultMessages
+	traverseRunHooks := cobra.EnableTraverseRunHooks
+	t.Cleanup(func() {
+		nicecmd.Environment, nicecmd.Debug = environment, debug
+		nicecmd.WarningHandler, nicecmd.DefaultMessages = warningHandler, messages
+		cobra.EnableTraverseRunHooks = traverseRunHooks
+	})
+}
@@ -0,0 +1,101 @@
+package nicecmdtest_test
+
+import (
+	"errors"
+	"github.com/mologie/nicecmd"
+	"github.com/mologie/nicecmd/nicecmdtest"
+	"github.com/spf13/cobra"
+	"strings"
+	"testing"
+)
+
+type RootConfig struct {
+	Verbose bool `flag:"persistent"`
+}
+
+type GreetConfig struct {
+	Name  string
+	Times int
+}
+
+func newRootCmd() *cobra.Command {
+	root := nicecmd.Command("TEST", nicecmd.PersistentPreRun(func(cfg RootConfig, cmd *cobra.Command, args []string) error {
+		if cfg.Verbose {
+			cmd.PrintErrln("verbose mode")
+		}
+		return nil
+	}), cobra.Command{Use: "root"}, RootConfig{})
+	root.AddCommand(nicecmd.Command("TEST_GREET", nicecmd.Run(func(cfg GreetConfig, cmd *cobra.Command, args []string) error {
+		for i := 0; i < cfg.Times; i++ {
+			cmd.Printf("Hello, %s!\n", cfg.Name)
+		}
+		return nil
+	}), cobra.Command{Use: "greet"}, GreetConfig{Name: "World", Times: 1}))
+	return root
+}
+
+func TestExecute(t *testing.T) {
+	root := newRootCmd()
+	res := nicecmdtest.Execute[GreetConfig](t, root,
+		nicecmdtest.Args("--verbose", "greet", "--times", "2"),
+		nicecmdtest.Env(map[string]string{"TEST_GREET_NAME": "Gopher"}))
+	if res.Err != nil {
+		t.Fatalf("execute: %v", res.Err)
+	}
+	if res.Config != (GreetConfig{Name: "Gopher", Times: 2}) {
+		t.Errorf("unexpected config: %+v", res.Config)
+	}
+	if res.Stdout != "Hello, Gopher!\nHello, Gopher!\n" {
+		t.Errorf("unexpected stdout: %q", res.Stdout)
+	}
+	if res.Stderr != "verbose mode\n" {
+		t.Errorf("unexpected stderr: %q", res.Stderr)
+	}
+	if res.Command.Name() != "greet" {
+		t.Errorf("expected greet to be executed, got %q", res.Command.Name())
+	}
+
+	// The original tree and a second execution must not be affected by the first one.
+	res = nicecmdtest.Execute[GreetConfig](t, root, nicecmdtest.Args("greet"))
+	if res.Err != nil || res.Config != (GreetConfig{Name: "World", Times: 1}) {
+		t.Errorf("expected defaults on second execution, got %+v, %v", res.Config, res.Err)
+	}
+}
+
+func TestExecute_IsolatedEnvironment(t *testing.T) {
+	t.Setenv("TEST_GREET_NAME", "Process")
+	res := nicecmdtest.Execute[GreetConfig](t, newRootCmd(), nicecmdtest.Args("greet"))
+	if res.Config.Name != "World" {
+		t.Errorf("expected process environment to be ignored, got name %q", res.Config.Name)
+	}
+}
+
+func TestExecute_InvalidEnvironment(t *testing.T) {
+	res := nicecmdtest.Execute[GreetConfig](t, newRootCmd(),
+		nicecmdtest.Args("greet"),
+		nicecmdtest.Env(map[string]string{"TEST_GREET_TIMES": "many"}))
+	var envErr *nicecmd.EnvError
+	if !errors.As(res.Err, &envErr) || envErr.Name != "TEST_GREET_TIMES" {
+		t.Errorf("expected EnvError for TEST_GREET_TIMES, got %v", res.Err)
+	}
+	if res.Command != nil {
+		t.Error("expected no command to be executed")
+	}
+}
+
+func TestExecute_RestoresGlobals(t *testing.T) {
+	t.Run("modify", func(t *testing.T) {
+		nicecmdtest.Execute[GreetConfig](t, newRootCmd(), nicecmdtest.Args("greet"))
+		nicecmd.Environment = false
+	})
+	if !nicecmd.Environment {
+		t.Error("expected nicecmd.Environment to be restored")
+	}
+}
+
+func TestExecute_NotNiceCmd(t *testing.T) {
+	res := nicecmdtest.Execute[struct{}](t, &cobra.Command{Use: "plain"})
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "cannot be cloned") {
+		t.Errorf("expected clone error, got %v", res.Err)
+	}
+}
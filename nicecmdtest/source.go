@@ -0,0 +1,73 @@
+package nicecmdtest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Step is a scripted response of a Source.
+type Step struct {
+	Values map[string]any // returned by Load, like a decoded configuration file
+	Delay  time.Duration  // before Load returns, e.g. to test timeouts
+	Err    error          // returned by Load instead of Values
+}
+
+// Source is an in-memory nicecmd.ConfigSource with scripted responses, for testing how a command
+// deals with values, latency and failures of a secret store or similar without running one:
+//
+//	src := nicecmdtest.NewSource("vault", nicecmdtest.Step{Values: map[string]any{"token": "t0"}})
+//	root := nicecmd.Command("APP", run, cobra.Command{Use: "app"}, Config{}, nicecmd.WithConfigSource(src))
+//
+// Each call of Load returns the next step, and the last step is repeated once all were returned.
+// It is safe for concurrent use.
+type Source struct {
+	name  string
+	steps []Step
+	mu    sync.Mutex
+	loads int
+}
+
+// NewSource returns a Source with the given name, which responds with steps in order. Without
+// steps, it has no values.
+func NewSource(name string, steps ...Step) *Source {
+	return &Source{name: name, steps: steps}
+}
+
+// Name returns the name given to NewSource.
+func (s *Source) Name() string {
+	return s.name
+}
+
+// Load returns the values or error of the next step after its delay. It returns the error of ctx
+// if ctx is done before the delay has passed.
+func (s *Source) Load(ctx context.Context) (map[string]any, error) {
+	s.mu.Lock()
+	var step Step
+	if len(s.steps) != 0 {
+		step = s.steps[min(s.loads, len(s.steps)-1)]
+	}
+	s.loads++
+	s.mu.Unlock()
+
+	if step.Delay > 0 {
+		timer := time.NewTimer(step.Delay)
+		defer timer.Stop()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	if step.Err != nil {
+		return nil, step.Err
+	}
+	return step.Values, nil
+}
+
+// Loads returns how often Load was called.
+func (s *Source) Loads() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loads
+}
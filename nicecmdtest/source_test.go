@@ -0,0 +1,62 @@
+package nicecmdtest_test
+
+import (
+	"context"
+	"errors"
+	"github.com/mologie/nicecmd"
+	"github.com/mologie/nicecmd/nicecmdtest"
+	"github.com/spf13/cobra"
+	"testing"
+	"time"
+)
+
+func TestSource(t *testing.T) {
+	errSealed := errors.New("vault is sealed")
+	src := nicecmdtest.NewSource("vault",
+		nicecmdtest.Step{Values: map[string]any{"name": "Vault"}},
+		nicecmdtest.Step{Err: errSealed},
+		nicecmdtest.Step{Values: map[string]any{"name": "Slow", "times": 2}, Delay: 10 * time.Millisecond},
+	)
+	root := nicecmd.Command("TEST", nicecmd.Run(func(cfg GreetConfig, cmd *cobra.Command, args []string) error {
+		return nil
+	}), cobra.Command{Use: "greet"}, GreetConfig{Name: "World", Times: 1}, nicecmd.WithConfigSource(src))
+
+	res := nicecmdtest.Execute[GreetConfig](t, root)
+	if res.Err != nil || res.Config.Name != "Vault" {
+		t.Errorf("expected the values of the first step, got %+v", res)
+	}
+
+	res = nicecmdtest.Execute[GreetConfig](t, root)
+	var cfgErr *nicecmd.ConfigError
+	if !errors.As(res.Err, &cfgErr) || cfgErr.Path != "vault" || !errors.Is(res.Err, errSealed) {
+		t.Errorf("expected *nicecmd.ConfigError of the second step, got %v", res.Err)
+	}
+
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		res = nicecmdtest.Execute[GreetConfig](t, root, nicecmdtest.Env(map[string]string{"TEST_TIMES": "3"}))
+		if res.Err != nil || res.Config.Name != "Slow" || res.Config.Times != 3 {
+			t.Errorf("expected the values of the last step below the environment, got %+v", res)
+		}
+		if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+			t.Errorf("expected the delay of the last step, took %s", elapsed)
+		}
+	}
+	if n := src.Loads(); n != 4 {
+		t.Errorf("expected 4 loads, got %d", n)
+	}
+}
+
+func TestSource_Canceled(t *testing.T) {
+	src := nicecmdtest.NewSource("slow", nicecmdtest.Step{Delay: time.Hour})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := src.Load(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the error of the context, got %v", err)
+	}
+
+	values, err := nicecmdtest.NewSource("empty").Load(context.Background())
+	if err != nil || values != nil {
+		t.Errorf("expected no values without steps, got %v, %v", values, err)
+	}
+}
@@ -0,0 +1,32 @@
+package nicecmd
+
+// Option configures a command created by Command or TryCommand, or bound via BindConfig or
+// TryBindConfig. Options that concern hooks only apply to Command and TryCommand.
+type Option func(*options)
+
+type options struct {
+	hookObserver  func(ev HookEvent)
+	types         *TypeRegistry
+	config        configOptions
+	environment   *bool // nil for the global Environment
+	env           envSource
+	envFold       bool
+	envSep        string
+	envDirs       []string
+	envFileSuffix string
+	expand        bool
+	flatEnv       bool
+	dotEnv        dotEnvOptions
+	files         fileSystem
+
+	// keepValues binds the struct with the values it has, without applying the default and
+	// envDefault tags, for inspecting the values of a copy. It is not set by any Option.
+	keepValues bool
+}
+
+func newOptions(opts []Option) (o options) {
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return
+}
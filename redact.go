@@ -0,0 +1,32 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"regexp"
+	"slices"
+)
+
+// redacted replaces the values of secret fields wherever they would be shown.
+const redacted = "<redacted>"
+
+// pflagInvalidArgument matches pflag's error for invalid flag values, which quotes the raw value.
+var pflagInvalidArgument = regexp.MustCompile(`^invalid argument ".*" for "((?:-\S, )?--(\S+))" flag: `)
+
+// redactFlagError wraps a Cobra flag error function, so that errors about invalid values of secret
+// flags do not contain the value.
+func redactFlagError(next func(*cobra.Command, error) error) func(*cobra.Command, error) error {
+	return func(cmd *cobra.Command, err error) error {
+		if m := pflagInvalidArgument.FindStringSubmatch(err.Error()); m != nil {
+			if flag := cmd.Flags().Lookup(m[2]); flag != nil && isSecret(flag) {
+				err = fmt.Errorf(DefaultMessages.InvalidSecretFlag, m[1])
+			}
+		}
+		return next(cmd, err)
+	}
+}
+
+func isSecret(flag *pflag.Flag) bool {
+	return slices.Contains(flag.Annotations[annotationSecret], "true")
+}
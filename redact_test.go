@@ -0,0 +1,115 @@
+package nicecmd
+
+import (
+	"bytes"
+	"errors"
+	"github.com/spf13/cobra"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRedact_Env(t *testing.T) {
+	if err := os.Setenv("NICECMD_REDACT_TOKEN", "hunter2"); err != nil {
+		t.Errorf("setenv: %v", err)
+		return
+	}
+	defer func() { _ = os.Unsetenv("NICECMD_REDACT_TOKEN") }()
+
+	var conf struct {
+		Token string `flag:"secret"`
+	}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("NICECMD_REDACT", cmd, &conf); err != nil {
+		t.Errorf("bind: %v", err)
+	}
+	if conf.Token != "hunter2" {
+		t.Errorf("expected secret to be applied, got %q", conf.Token)
+	}
+	usage := cmd.Flags().FlagUsages()
+	if strings.Contains(usage, "hunter2") || !strings.Contains(usage, "NICECMD_REDACT_TOKEN=<redacted>") {
+		t.Errorf("expected secret to be redacted from usage, got:\n%s", usage)
+	}
+}
+
+func TestRedact_InvalidEnv(t *testing.T) {
+	if err := os.Setenv("NICECMD_REDACT_PIN", "hunter2"); err != nil {
+		t.Errorf("setenv: %v", err)
+		return
+	}
+	defer func() { _ = os.Unsetenv("NICECMD_REDACT_PIN") }()
+
+	var conf struct {
+		Pin int `flag:"secret"`
+	}
+	cmd := &cobra.Command{}
+	buf := &bytes.Buffer{}
+	cmd.SetErr(buf)
+	Debug = true
+	defer func() { Debug = false }()
+	err := TryBindConfig("NICECMD_REDACT", cmd, &conf)
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || !envErr.Secret || envErr.Value != "" || envErr.Err != nil {
+		t.Errorf("expected secret EnvError without value, got %#v", envErr)
+	}
+	want := "environment variable NICECMD_REDACT_PIN: invalid value <redacted>"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected error %q, got %v", want, err)
+	}
+	if out := buf.String(); strings.Contains(out, "hunter2") {
+		t.Errorf("expected secret to be redacted from trace, got:\n%s", out)
+	}
+	if usage := cmd.Flags().FlagUsages(); strings.Contains(usage, "hunter2") {
+		t.Errorf("expected secret to be redacted from usage, got:\n%s", usage)
+	}
+}
+
+func TestRedact_FlagError(t *testing.T) {
+	type Conf struct {
+		Pin  int `flag:"secret,persistent" param:"pin,p"`
+		Port int
+	}
+	run := func(cfg Conf, cmd *cobra.Command, args []string) error { return nil }
+	root := Command("TEST", Run(run), cobra.Command{Use: "root"}, Conf{})
+	root.AddCommand(&cobra.Command{Use: "sub", Run: func(cmd *cobra.Command, args []string) {}})
+	root.SilenceErrors = true
+	root.SilenceUsage = true
+
+	tt := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"--pin", "hunter2"}, want: `invalid argument <redacted> for "-p, --pin" flag`},
+		{args: []string{"sub", "--pin=hunter2"}, want: `invalid argument <redacted> for "-p, --pin" flag`},
+		{args: []string{"--port", "foo"}, want: `invalid argument "foo" for "--port" flag: `},
+	}
+	for _, test := range tt {
+		root.SetArgs(test.args)
+		err := root.Execute()
+		if err == nil || !strings.HasPrefix(err.Error(), test.want) {
+			t.Errorf("%v: expected error %q, got %v", test.args, test.want, err)
+		}
+	}
+}
+
+func TestRedact_Default(t *testing.T) {
+	type Conf struct {
+		Token string `flag:"secret"`
+		Pin   int    `flag:"secret"`
+	}
+	cmd := Command("TEST", Run(func(cfg Conf, cmd *cobra.Command, args []string) error {
+		return nil
+	}), cobra.Command{Use: "test"}, Conf{Token: "dev-secret"})
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"--help"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if help := buf.String(); strings.Contains(help, "dev-secret") || !strings.Contains(help, `(default "<redacted>")`) {
+		t.Errorf("expected secret default to be redacted from help, got:\n%s", help)
+	}
+	if fields := Fields(cmd); fields[0].Default != redacted || fields[1].Default != "0" {
+		t.Errorf("expected only the secret default to be redacted, got %q and %q", fields[0].Default, fields[1].Default)
+	}
+}
@@ -1,22 +1,33 @@
 package nicecmd
 
 import (
+	"cmp"
 	"encoding"
 	"fmt"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"net"
+	"net/netip"
+	"net/url"
 	"os"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 // Environment is a kill-switch for BindConfig to disable environment variable processing.
-// Set this globally if you use another library for environment variables, e.g. Viper.
+// Set this globally if you use another library for environment variables, e.g. Viper. Commands
+// that were given WithEnvironment ignore it.
 var Environment = true
 
+// Debug makes BindConfig trace every binding decision to the command's stderr: Which field and
+// tags produced which flag, which environment variable was consulted, and which values were
+// applied. It is enabled by setting the NICECMD_DEBUG environment variable to a non-empty value.
+var Debug = os.Getenv("NICECMD_DEBUG") != ""
+
 const (
 	// optPersistent adds the flag to the persistent flag set instead of the command flag set.
 	// Persistent flags are a Cobra feature where the parameter is allowed to appear anywhere, not
@@ -25,12 +36,44 @@ const (
 
 	// optRequired marks a flag as required
 	optRequired = "required"
+
+	// optSecret keeps the flag's value out of usage strings and error messages.
+	optSecret = "secret"
+
+	// optHidden hides the flag in help texts, while it can still be set like any other.
+	optHidden = "hidden"
+
+	// optInline binds the fields of a nested struct without the prefixes derived from its field.
+	optInline = "inline"
+
+	// optExists requires paths of flags with the complete tag to exist.
+	optExists = "exists"
+
+	// optNegatable adds a --no-<name> flag that sets a bool flag to false.
+	optNegatable = "negatable"
+
+	// optLiteral takes values of environment variables literally, without resolving file:// URLs.
+	optLiteral = "literal"
+)
+
+const (
+	// annotationEnv is the flag annotation holding the environment variable bound to a flag.
+	annotationEnv = "nicecmd_env"
+
+	// annotationSecret marks a flag whose value must not be shown.
+	annotationSecret = "nicecmd_secret"
+)
+
+const (
+	envMapKeep  = "keep"
+	envMapLower = "lower"
 )
 
 const (
 	encodingBase64 = "base64"
 	encodingCSV    = "csv"
 	encodingCount  = "count"
+	encodingDays   = "days"
 	encodingHex    = "hex"
 	encodingRaw    = "raw"
 )
@@ -41,37 +84,194 @@ const (
 // Struct tags:
 // - flag: Set of the flags defined above, separated by commas.
 // - param: "foo,f" for --foo=bar or -f x. Defaults to kebab-case of field name without short name.
-// - encoding: Type-specific encoding, e.g. "base64" for []byte.
+// - encoding: Type-specific encoding, e.g. "base64" for []byte, or "days" for durations like 2w.
 // - env: Environment variable name, "-" for none, defaults to prefixed screaming snake case.
+// - envSeparator: Separator of slice values in the environment variable, e.g. ":", instead of CSV.
+// - envDeprecated: Former variable names, separated by "|", read with a warning as a fallback.
+// - default: Default value in the format of the flag, if the field is zero or has the same value.
+// - envDefault: Default value in the format of the environment variable, replacing the field's.
+// - envMap: "keep" or "lower" to read maps from one variable per key, e.g. FOO_LABELS_<KEY>.
+// - envTransform: Transformations of the variable's value, separated by commas, see RegisterEnvTransform.
+// - choices: Allowed values, separated by commas, for validation, usage and shell completion.
+// - min, max: Bounds of numbers and durations, in the format of the flag, e.g. "1s".
+// - pattern: Regular expression that strings must match, e.g. "^[a-z0-9-]+$".
+// - schemes: Allowed schemes of URLs, separated by commas, e.g. "https,wss".
+// - complete: "file", "file,yaml,yml" or "dir" to complete paths, see also the exists option.
+// - expand: "path" to expand ~, ~user and $NAME in values of strings, wherever they come from.
+// - group: "name,kind,..." to group flags: mutex, together, or required for at least one of them.
+// - aliases: Further flag names, separated by commas, e.g. former names that scripts still use.
+// - deprecated: Notice shown when the flag is used, e.g. "use --bar instead". Hides the flag.
+// - helpgroup: Title of the section that lists the flag in help texts, e.g. "Networking".
+// - arg: Index of the positional argument that sets the field instead of a flag, e.g. "0", or
+// "rest" for a slice of the remaining arguments.
 // - usage: Flag usage string. Environment variable name is appended if set.
 //
+// The env tag may list alternative names separated by "|", e.g. `env:"DATABASE_URL|FOO_DB_URL"`,
+// and the first variable that is set wins. The aliases tag adds hidden flags for the same value,
+// e.g. `aliases:"old-name"` for --old-name, along with FOO_OLD_NAME unless the env tag is given.
+//
+// Flags with the secret option never have their value shown in usage strings or error messages.
+// Flags with the hidden option are left out of help texts, but can still be set. Bool flags with
+// the negatable option get a --no-<name> counterpart, for turning off features that default to on.
+// Flags with the exists option require the file or directory of their complete tag to exist. Nested structs
+// with the inline option contribute their fields without the prefixes derived from their field.
+// Like options, the helpgroup tag of a nested struct applies to all of its fields.
+//
+// Environment variables with a file:// URL as value, e.g. FOO_TLS_KEY=file:///run/secrets/key, set
+// the flag to the contents of that file, minus one trailing newline. Flags with the literal option
+// take such values as they are instead.
+//
 // The env prefix defaults to envPrefix + "_". For structs, the prefix is further extended with the
-// screaming snake case of the field name where the struct is embedded.
-func BindConfig(envPrefix string, cmd *cobra.Command, cfg any) bool {
+// screaming snake case of the field name where the struct is embedded, and "_". See
+// WithEnvNestingSeparator for using another separator. The envPrefix tag of a nested struct
+// replaces the part derived from its field name, e.g. `param:"db" envPrefix:"DATABASE"` for
+// --db-host and FOO_DATABASE_HOST, whereas its env tag replaces the whole prefix.
+//
+// BindConfig panics if cfg cannot be bound, e.g. because of a mistake in its tags. Environment
+// variables with invalid values are printed to cmd and make BindConfig return false.
+func BindConfig(envPrefix string, cmd *cobra.Command, cfg any, opts ...Option) bool {
+	envErrs, err := bindConfig(envPrefix, cmd, cfg, newOptions(opts), envSource{})
+	if err != nil {
+		panic(err.Error())
+	}
+	for _, envErr := range envErrs {
+		cmd.Println(cmd.ErrPrefix(), envErr)
+	}
+	return len(envErrs) == 0
+}
+
+// TryBindConfig is like BindConfig, but returns an error instead of panicking or printing. The
+// error is a *BindError if cfg cannot be bound, or one or more joined *EnvError otherwise.
+func TryBindConfig(envPrefix string, cmd *cobra.Command, cfg any, opts ...Option) error {
+	envErrs, err := bindConfig(envPrefix, cmd, cfg, newOptions(opts), envSource{})
+	if err != nil {
+		return err
+	}
+	return joinEnvErrors(envErrs)
+}
+
+func bindConfig(envPrefix string, cmd *cobra.Command, cfg any, o options, env envSource,
+) ([]*EnvError, error) {
 	if envPrefix != "" {
 		if strings.ToUpper(envPrefix) != envPrefix {
-			panic("envPrefix must be all uppercase")
+			return nil, &BindError{Msg: "envPrefix must be all uppercase"}
 		}
 		if strings.HasSuffix(envPrefix, "_") {
-			panic("envPrefix must not end with an underscore, it is added automatically")
+			return nil, &BindError{Msg: "envPrefix must not end with an underscore, it is added automatically"}
 		}
-		envPrefix += "_"
+		envPrefix += o.envNestingSeparator()
 	}
 	v := reflect.ValueOf(cfg)
 	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
-		panic("cfg must be a struct pointer")
+		return nil, &BindError{Msg: "cfg must be a struct pointer"}
 	}
-	var fail bool
-	recurseStruct("", envPrefix, fieldOpts{}, cmd, v.Elem(), &fail)
-	return !fail
+	if err := o.config.checkPrecedence(); err != nil {
+		return nil, err
+	}
+	if env.lookup == nil {
+		env = o.env
+	}
+	if env.lookup == nil {
+		env = osEnv
+	}
+	b := &binder{cmd: cmd, env: env, envDirs: o.envDirs, envFileSuffix: o.envFileSuffix, envSep: o.envNestingSeparator(), types: o.types, files: o.files, keepValues: o.keepValues}
+	if b.types == nil {
+		b.types = DefaultTypes
+	}
+	b.environment = Environment
+	if o.environment != nil {
+		b.environment = *o.environment
+	}
+	if b.environment {
+		b.loadEnvFiles(o.dotEnv)
+	}
+	if o.envFold {
+		b.env = b.env.fold()
+	}
+	if o.expand {
+		b.expander = &expander{env: b.env}
+	}
+	err := b.bindStruct("", "", envPrefix, fieldOpts{}, v.Elem())
+	if err == nil {
+		err = b.markGroups()
+	}
+	if err == nil {
+		err = b.bindArgs()
+	}
+	if err == nil && !b.envFound {
+		b.hintEnvPrefix(envPrefix, b.env.environ)
+	}
+	if err == nil && b.secrets {
+		cmd.SetFlagErrorFunc(redactFlagError(cmd.FlagErrorFunc()))
+	}
+	if err == nil && b.helpGroups {
+		groupUsageTemplate(cmd)
+	}
+	b.warn.flush(cmd)
+	if err == nil {
+		err = storeBinding(cmd, &binding{cfg: cfg, fields: b.fields, expander: b.expander, files: b.files})
+	}
+	return b.envErrs, err
+}
+
+// binder holds the state of a single BindConfig call.
+type binder struct {
+	cmd           *cobra.Command
+	env           envSource
+	environment   bool // whether to apply environment variables, see WithEnvironment
+	envDirs       []string
+	envFileSuffix string
+	envSep        string    // separator of nested names, see WithEnvNestingSeparator
+	expander      *expander // nil unless WithExpansion is given
+	types         *TypeRegistry
+	files         fileSystem
+	keepValues    bool // whether to skip the default and envDefault tags, see options.keepValues
+	warn          warnings
+	envErrs       []*EnvError
+	envNames      []string          // all environment variables consulted
+	envFound      bool              // whether any of them was set
+	envFields     map[string]string // field bound to each environment variable
+	secrets       bool              // whether any flag is secret
+	helpGroups    bool              // whether any flag has a helpgroup tag
+	fields        []FieldInfo
+	groups        []*flagGroup   // of group tags, in order of appearance
+	argSet        *pflag.FlagSet // see argFlags
+	args          []boundArg     // of arg tags, in order of appearance
+}
+
+// errorf returns a *BindError for the given field.
+func (b *binder) errorf(field string, format string, args ...any) error {
+	return &BindError{Field: field, Msg: fmt.Sprintf(format, args...)}
 }
 
-func recurseStruct(paramPrefix, envPrefix string, parentOpts fieldOpts,
-	cmd *cobra.Command, struct_ reflect.Value, fail *bool,
-) {
-	type_ := struct_.Type()
-	for i := 0; i < type_.NumField(); i++ {
-		tags := getFieldTags(paramPrefix, envPrefix, type_.Field(i))
+// trace prints a binding decision if Debug is enabled.
+func (b *binder) trace(format string, args ...any) {
+	//goland:noinspection GoBoolExpressions
+	if Debug {
+		b.cmd.PrintErrf("nicecmd: "+format+"\n", args...)
+	}
+}
+
+func (b *binder) bindStruct(fieldPrefix, paramPrefix, envPrefix string, parentOpts fieldOpts,
+	struct_ reflect.Value,
+) error {
+	cmd := b.cmd
+	metas := typeFields(struct_.Type())
+	b.fields = slices.Grow(b.fields, len(metas))
+	b.envNames = slices.Grow(b.envNames, len(metas))
+	for i, meta := range metas {
+		if meta.cmd != "" {
+			continue // sub-command, see New
+		}
+		field := meta.field
+		fieldName := fieldPrefix + field.Name
+		tags, err := getFieldTags(paramPrefix, envPrefix, meta)
+		if err != nil {
+			return &BindError{Field: fieldName, Msg: err.Error()}
+		}
+		for _, opt := range tags.UnknownOpts() {
+			b.warn.add("unknown flag option %q for %q", opt, tags.name)
+		}
 		opts := tags.Opts().Or(parentOpts)
 		value := struct_.Field(i)
 
@@ -88,6 +288,69 @@ func recurseStruct(paramPrefix, envPrefix string, parentOpts fieldOpts,
 		// I'll add it here. However, custom or other stdlib types won't be supported directly by
 		// matching their type here, as that would require adding additional packages.
 		in := value.Addr().Interface()
+		if reg := b.types.lookup(value.Type()); reg != nil {
+			in = &registeredValue{ptr: value.Addr(), reg: reg}
+		}
+		if value.Kind() == reflect.Struct && value.Type().NumField() > 0 && !isFlagValue(in) {
+			nestedParam, nestedEnv := tags.name+"-", tags.env+b.envSep
+			if tags.envPrefix != "" {
+				if err := checkEnvPrefixTag(meta); err != nil {
+					return b.errorf(fieldName, "%s", err)
+				}
+				nestedEnv = envPrefix + tags.envPrefix + b.envSep
+			}
+			if tags.hasOption(optInline) {
+				nestedParam, nestedEnv = paramPrefix, envPrefix
+			}
+			if tags.arg != "" {
+				return b.errorf(fieldName, "arg for %q requires a single value, got struct %s", tags.name, value.Type())
+			}
+			b.trace("%s: nested struct with tags `%s`, flag prefix --%s, env prefix %s",
+				fieldName, field.Tag, nestedParam, nestedEnv)
+			if err := b.bindStruct(fieldName+".", nestedParam, nestedEnv, opts, value); err != nil {
+				return err
+			}
+			continue // do not process an environment variable
+		}
+		if tags.hasOption(optInline) {
+			return b.errorf(fieldName, "inline for %q requires a struct, got %s", tags.name, value.Type())
+		}
+		if tags.envPrefix != "" {
+			return b.errorf(fieldName, "envPrefix for %q requires a struct, got %s", tags.name, value.Type())
+		}
+		if tags.arg != "" {
+			fs = b.argFlags() // parsed like a flag, but not part of the command
+		}
+		if fs.Lookup(tags.name) != nil {
+			return b.errorf(fieldName, "flag %q is already defined", tags.name)
+		}
+		if owner := persistentOwner(cmd.Parent(), tags.name); owner != nil && tags.arg == "" {
+			return b.errorf(fieldName, "flag %q shadows the persistent flag of %q", tags.name, owner.CommandPath())
+		}
+		if tags.abbrev != "" && fs.ShorthandLookup(tags.abbrev) != nil {
+			return b.errorf(fieldName, "shorthand %q for %q is already defined", tags.abbrev, tags.name)
+		}
+		if tags.defValue != "" && tags.envDefault != "" {
+			return b.errorf(fieldName, "default and envDefault for %q are mutually exclusive", tags.name)
+		}
+		if tags.defValue != "" && !b.keepValues {
+			parsed, err := b.parseTagDefault(value, tags.encoding, tags.defValue)
+			if err != nil {
+				return b.errorf(fieldName, "default %q for %q: %s", tags.defValue, tags.name, err)
+			}
+			// The struct may repeat the default, e.g. when it was derived from a bound one
+			if !value.IsZero() && !reflect.DeepEqual(value.Interface(), parsed.Interface()) {
+				return b.errorf(fieldName, "default %q for %q differs from the value %v of the struct", tags.defValue, tags.name, value.Interface())
+			}
+			value.Set(parsed)
+		}
+		if tags.envDefault != "" && !b.keepValues {
+			parsed, err := b.parseTagDefault(value, tags.encoding, tags.envDefault)
+			if err != nil {
+				return b.errorf(fieldName, "envDefault %q for %q: %s", tags.envDefault, tags.name, err)
+			}
+			value.Set(parsed)
+		}
 		switch p := in.(type) {
 		case *bool:
 			fs.BoolVarP(p, tags.name, tags.abbrev, *p, tags.usage)
@@ -100,7 +363,7 @@ func recurseStruct(paramPrefix, envPrefix string, parentOpts fieldOpts,
 			case encodingHex:
 				fs.BytesHexVarP(p, tags.name, tags.abbrev, *p, tags.usage)
 			default:
-				panic(fmt.Sprintf(`expected encoding:"base64" or encoding:"hex" for bytes slice %q, got encoding %q`, tags.name, tags.encoding))
+				return b.errorf(fieldName, `expected encoding:"base64" or encoding:"hex" for bytes slice %q, got encoding %q`, tags.name, tags.encoding)
 			}
 		case *int:
 			switch tags.encoding {
@@ -108,11 +371,8 @@ func recurseStruct(paramPrefix, envPrefix string, parentOpts fieldOpts,
 				fs.IntVarP(p, tags.name, tags.abbrev, *p, tags.usage)
 			case encodingCount:
 				fs.CountVarP(p, tags.name, tags.abbrev, tags.usage)
-				if tags.HasEnv() {
-					panic(fmt.Sprintf(`count encoding for %q requires env:"-", cannot count env vars`, tags.name))
-				}
 			default:
-				panic(fmt.Sprintf(`expected no encoding or encoding:"count" for int %q, got encoding %q`, tags.name, tags.encoding))
+				return b.errorf(fieldName, `expected no encoding or encoding:"count" for int %q, got encoding %q`, tags.name, tags.encoding)
 			}
 		case *[]int:
 			fs.IntSliceVarP(p, tags.name, tags.abbrev, *p, tags.usage)
@@ -160,11 +420,11 @@ func recurseStruct(paramPrefix, envPrefix string, parentOpts fieldOpts,
 				fs.StringSliceVarP(p, tags.name, tags.abbrev, *p, tags.usage)
 			case encodingRaw:
 				fs.StringArrayVarP(p, tags.name, tags.abbrev, *p, tags.usage)
-				if tags.HasEnv() {
-					panic(fmt.Sprintf(`encoding:"raw" for string slice %q requires env:"-"`, tags.name))
+				if tags.HasEnv() && tags.envSeparator == "" {
+					return b.errorf(fieldName, `encoding:"raw" for string slice %q requires env:"-" or an envSeparator tag`, tags.name)
 				}
 			default:
-				panic(fmt.Sprintf(`expected encoding:"csv" or encoding:"raw" for string slice %q, got encoding %q`, tags.name, tags.encoding))
+				return b.errorf(fieldName, `expected encoding:"csv" or encoding:"raw" for string slice %q, got encoding %q`, tags.name, tags.encoding)
 			}
 		case *map[string]int:
 			fs.StringToIntVarP(p, tags.name, tags.abbrev, *p, tags.usage)
@@ -173,118 +433,581 @@ func recurseStruct(paramPrefix, envPrefix string, parentOpts fieldOpts,
 		case *map[string]string:
 			fs.StringToStringVarP(p, tags.name, tags.abbrev, *p, tags.usage)
 		case *time.Duration:
-			fs.DurationVarP(p, tags.name, tags.abbrev, *p, tags.usage)
+			switch tags.encoding {
+			case "":
+				fs.DurationVarP(p, tags.name, tags.abbrev, *p, tags.usage)
+			case encodingDays:
+				fs.VarP(newDaysValue(p), tags.name, tags.abbrev, tags.usage)
+			default:
+				return b.errorf(fieldName, `expected no encoding or encoding:"days" for duration %q, got encoding %q`, tags.name, tags.encoding)
+			}
 		case *[]time.Duration:
 			fs.DurationSliceVarP(p, tags.name, tags.abbrev, *p, tags.usage)
+		case **time.Location:
+			fs.VarP(&locationValue{ptr: p}, tags.name, tags.abbrev, tags.usage)
+		case *url.URL:
+			fs.VarP(&urlValue{value: p}, tags.name, tags.abbrev, tags.usage)
+		case **url.URL:
+			fs.VarP(&urlValue{ptr: p}, tags.name, tags.abbrev, tags.usage)
 		case *net.IP:
 			fs.IPVarP(p, tags.name, tags.abbrev, *p, tags.usage)
 		case *net.IPMask:
 			fs.IPMaskVarP(p, tags.name, tags.abbrev, *p, tags.usage)
 		case *net.IPNet:
 			fs.IPNetVarP(p, tags.name, tags.abbrev, *p, tags.usage)
+		case *netip.Addr:
+			fs.VarP(&textMarshalerValue{text: p, desc: "ip"}, tags.name, tags.abbrev, tags.usage)
+		case *netip.Prefix:
+			fs.VarP(&textMarshalerValue{text: p, desc: "ipPrefix"}, tags.name, tags.abbrev, tags.usage)
+		case *netip.AddrPort:
+			fs.VarP(&textMarshalerValue{text: p, desc: "ipPort"}, tags.name, tags.abbrev, tags.usage)
 		default:
-			if pFlag, ok := in.(pflag.Value); ok {
+			var checkErr error
+			if regValue, ok := in.(*registeredValue); ok {
+				// Registered types take precedence, so that they can replace built-in types
+				fs.VarP(regValue, tags.name, tags.abbrev, tags.usage)
+				checkErr = regValue.checkRoundTrip(opts)
+			} else if pFlag, ok := in.(pflag.Value); ok {
 				// A bunch of libraries, such as K8s, use pflag.Value for various types that also
 				// get used as flags with Cobra in frontend tools. This is a catch-all for those.
 				fs.VarP(pFlag, tags.name, tags.abbrev, tags.usage)
+				checkErr = checkValueRoundTrip(value, opts)
 			} else if textFlag, ok := in.(textUnmarshalledFlag); ok {
 				// This is our magic extension point, where any TextUnmarshaler+Stringer can become
 				// a flag if it additionally defines CmdTypeDesc() for help messages. The latter
 				// method also avoids accidentally flag-i-fying a type that is not meant to be one.
 				fs.VarP(newTextValue(textFlag), tags.name, tags.abbrev, tags.usage)
-			} else if value.Kind() == reflect.Struct && value.Type().NumField() > 0 {
-				recurseStruct(tags.name+"-", tags.env+"_", opts, cmd, value, fail)
-				continue // do not process an environment variable
+				checkErr = checkRoundTrip(value, opts)
 			} else {
-				panic(fmt.Sprintf("unsupported field type %T", p))
+				return b.errorf(fieldName, "unsupported field type %T", p)
+			}
+			// Custom types bring their own parser and formatter, which may not agree with each
+			// other. Catch this here rather than with a confusing help text or parse error later.
+			if checkErr != nil {
+				return b.errorf(fieldName, "default value of %q: %s", tags.name, checkErr)
 			}
 		}
 
 		param := fs.Lookup(tags.name)
 		if param == nil {
-			panic(fmt.Sprintf("flag %q not found after it was added", tags.name))
+			return b.errorf(fieldName, "flag %q not found after it was added", tags.name)
+		}
+		if opts.secret && !value.IsZero() {
+			// pflag shows the default in usage and FieldInfo exposes it, tell only that there is one
+			param.DefValue = redacted
+		}
+		if tags.envMap != "" && tags.envMap != envMapKeep && tags.envMap != envMapLower {
+			return b.errorf(fieldName, `expected envMap:"keep" or envMap:"lower" for %q, got %q`, tags.name, tags.envMap)
+		} else if tags.envMap != "" && !isMapFlag(param) {
+			return b.errorf(fieldName, "envMap for %q requires a map type, got %s", tags.name, param.Value.Type())
+		}
+		if _, ok := param.Value.(pflag.SliceValue); tags.envSeparator != "" && !ok {
+			return b.errorf(fieldName, "envSeparator for %q requires a slice type, got %s", tags.name, param.Value.Type())
+		}
+		for _, name := range tags.envTransform {
+			if b.types.lookupTransform(name) == nil {
+				return b.errorf(fieldName, "unknown envTransform %q for %q", name, tags.name)
+			}
+		}
+		if tags.expand != "" {
+			if err := b.expandPaths(param, value, tags); err != nil {
+				return b.errorf(fieldName, "expansion of %q: %s", tags.name, err)
+			}
+		}
+		if err := b.constrain(param, value, tags); err != nil {
+			return b.errorf(fieldName, "constraints of %q: %s", tags.name, err)
+		}
+		if tags.arg != "" {
+			if err := b.addArg(fieldName, meta, tags, opts, param); err != nil {
+				return err
+			}
+			continue // not a flag, and not bound to an environment variable
+		}
+		if param.Value.Type() == "count" && tags.HasEnv() {
+			param.Value = &countValue{Value: param.Value, field: value}
+		}
+		if err := b.registerCompletion(fs, param, tags); err != nil {
+			return b.errorf(fieldName, "completion of %q: %s", tags.name, err)
+		}
+		if err := b.addToGroups(fieldName, tags, param); err != nil {
+			return err
+		}
+		//goland:noinspection GoBoolExpressions
+		if Debug { // avoid formatting arguments for every field of large configurations
+			b.trace("%s: %s with tags `%s` bound to flag --%s (shorthand %q, type %s, persistent %t)",
+				fieldName, value.Type(), field.Tag, param.Name, param.Shorthand, param.Value.Type(),
+				opts.persistent)
+		}
+
+		info := FieldInfo{
+			Field:      fieldName,
+			Type:       value.Type(),
+			Flag:       param.Name,
+			Shorthand:  param.Shorthand,
+			Aliases:    tags.flagAliases,
+			Default:    param.DefValue,
+			Usage:      tags.usage,
+			Required:   opts.required,
+			Persistent: opts.persistent,
+			Secret:     opts.secret,
+			Hidden:     opts.hidden,
+			Deprecated: tags.deprecated,
+		}
+		if b.environment && tags.HasEnv() {
+			info.Env = tags.env
+			info.EnvAliases = tags.envAliases
+			info.EnvDeprecated = tags.envDeprecated
+		}
+		b.fields = append(b.fields, info)
+
+		if tags.HasEnv() {
+			for _, name := range tags.EnvNames() {
+				if prev, ok := b.envFields[name]; ok {
+					return b.errorf(fieldName, "environment variable %s is already bound to %s", name, prev)
+				}
+				if b.envFields == nil {
+					b.envFields = make(map[string]string)
+				}
+				b.envFields[name] = fieldName
+			}
+			setAnnotation(param, annotationEnv, tags.EnvNames()...)
+		}
+
+		if opts.secret {
+			setAnnotation(param, annotationSecret, "true")
+			b.secrets = true
+		}
+
+		param.Hidden = opts.hidden
+		if opts.helpGroup != "" {
+			setAnnotation(param, annotationHelpGroup, opts.helpGroup)
+			b.helpGroups = true
+		}
+		if tags.deprecated != "" {
+			// pflag hides deprecated flags, and prints the notice when they are used
+			param.Deprecated = tags.deprecated
+			if param.Shorthand != "" {
+				param.ShorthandDeprecated = tags.deprecated
+			}
 		}
 
 		if opts.required {
 			if err := cobra.MarkFlagRequired(fs, param.Name); err != nil {
-				panic(fmt.Sprintf("failed to mark flag %q as required: %s", tags.name, err))
+				return b.errorf(fieldName, "failed to mark flag %q as required: %s", tags.name, err)
 			}
-			if len(param.Usage) != 0 {
-				param.Usage += " "
+		}
+
+		if tags.hasOption(optNegatable) {
+			if err := b.addNegation(fs, param); err != nil {
+				return b.errorf(fieldName, "negatable %q: %s", tags.name, err)
 			}
-			param.Usage += "(required)"
+		}
+		for _, alias := range tags.flagAliases {
+			if fs.Lookup(alias) != nil {
+				return b.errorf(fieldName, "alias %q of %q is already defined", alias, tags.name)
+			}
+			if owner := persistentOwner(cmd.Parent(), alias); owner != nil {
+				return b.errorf(fieldName, "alias %q of %q shadows the persistent flag of %q", alias, tags.name, owner.CommandPath())
+			}
+			aliasFlag := &pflag.Flag{
+				Name:        alias,
+				Usage:       tags.usage,
+				Value:       &aliasValue{Value: param.Value, flag: param},
+				DefValue:    param.DefValue,
+				NoOptDefVal: param.NoOptDefVal,
+				Hidden:      true,
+			}
+			if opts.secret {
+				setAnnotation(aliasFlag, annotationSecret, "true")
+			}
+			fs.AddFlag(aliasFlag)
 		}
 
-		// Apply environment variable
-		//goland:noinspection GoBoolExpressions
-		if Environment && tags.HasEnv() {
-			if len(param.Usage) != 0 {
-				param.Usage += " "
-			}
-			if envVal := os.Getenv(tags.env); envVal != "" {
-				ansiColor := "32" // green
-				if err := param.Value.Set(envVal); err != nil {
-					cmd.Printf("Error: environment variable %s: %s\n", tags.env, err)
-					*fail = true
-					ansiColor = "31" // red
+		// Apply environment variable, the first one set of the field's aliases
+		if !b.environment {
+			b.trace("%s: environment processing is disabled", fieldName)
+		} else if !tags.HasEnv() {
+			b.trace("%s: environment variable disabled via env:\"-\"", fieldName)
+		} else {
+			names := tags.EnvNames()
+			b.envNames = append(b.envNames, names...)
+			state := envUnset
+			var envName, envVal string
+			var envList []string // values of indexed variables, see lookupEnvList
+			var envErr *EnvError
+			for _, envName = range names {
+				if envVal, envErr = b.lookupEnv(envName); envErr != nil || envVal != "" {
+					break
+				}
+			}
+			if tags.envMap != "" && envErr == nil && envVal == "" {
+				for _, name := range names {
+					if envVal = b.lookupEnvMap(name, tags.envMap == envMapLower); envVal != "" {
+						envName = name + "_*"
+						break
+					}
+				}
+			} else if _, ok := param.Value.(pflag.SliceValue); ok && envErr == nil && envVal == "" {
+				for _, name := range names {
+					if envList, envErr = b.lookupEnvList(name); envErr != nil || envList != nil {
+						envName = name + "_*"
+						break
+					}
+				}
+			}
+			if envErr == nil && envVal == "" && envList == nil {
+				b.envNames = append(b.envNames, tags.envDeprecated...)
+				for _, old := range tags.envDeprecated {
+					if envVal, envErr = b.lookupEnv(old); envErr != nil || envVal != "" {
+						envName = old
+						b.warn.add(DefaultMessages.EnvDeprecated, old, tags.env)
+						break
+					}
+				}
+			}
+			if envErr != nil {
+				// The variable's file cannot be read, and the error does not reveal its value
+				b.envErrs = append(b.envErrs, envErr)
+				b.trace("%s: environment variable %s cannot be read: %s", fieldName, envErr.Name, envErr.Err)
+			} else if envVal != "" || envList != nil {
+				b.envFound = true
+				state = envApplied
+				var err error
+				envVal, err = b.setEnv(param, tags, envVal, envList)
+				if err != nil {
+					envErr := &EnvError{Name: envName, Value: envVal, Err: err}
+					if opts.secret {
+						envErr = &EnvError{Name: envName, Secret: true}
+						err = envErr
+					}
+					b.envErrs = append(b.envErrs, envErr)
+					state = envInvalid
+					b.trace("%s: environment variable %s=%s rejected: %s", fieldName, envName, traceValue(opts, envVal), err)
+				} else {
+					b.trace("%s: environment variable %s=%s applied to --%s", fieldName, envName, traceValue(opts, envVal), param.Name)
 				}
 				param.Changed = true
-				param.Usage += fmt.Sprintf("(\033[%smenv %s=%q\033[0m)", ansiColor, tags.env, envVal)
-			} else {
-				param.Usage += fmt.Sprintf("(env %s)", tags.env)
+				setAnnotation(param, annotationEnvName, envName)
+				if !opts.secret {
+					setAnnotation(param, annotationEnvValue, envVal)
+				}
+			} else if Debug {
+				b.trace("%s: environment variable %s is not set, keeping default %s", fieldName, strings.Join(names, "|"), traceValue(opts, param.DefValue))
 			}
+			setAnnotation(param, annotationEnvState, state)
 		}
+		decorateUsage(param)
 	}
+	return nil
+}
+
+// setEnv applies the value of an environment variable to param, or the values of indexed variables
+// if list is non-nil. It returns the value as applied, i.e. after expansion.
+func (b *binder) setEnv(param *pflag.Flag, tags fieldTags, value string, list []string) (string, error) {
+	if list != nil {
+		values := make([]string, len(list))
+		for i, elem := range list {
+			var err error
+			if list[i], values[i], err = b.resolveEnv(elem, tags); err != nil {
+				return strings.Join(list, ","), err
+			}
+		}
+		// Replace, unlike Set, lets the first flag on the command line replace the value
+		return strings.Join(list, ","), param.Value.(pflag.SliceValue).Replace(values)
+	}
+	value, resolved, err := b.resolveEnv(value, tags)
+	if err != nil {
+		return value, err
+	}
+	if count, ok := param.Value.(*countValue); ok {
+		return value, count.setEnv(resolved)
+	}
+	if tags.envSeparator != "" {
+		return value, param.Value.(pflag.SliceValue).Replace(strings.Split(resolved, tags.envSeparator))
+	}
+	return value, param.Value.Set(resolved)
+}
+
+// resolveEnv expands the value of an environment variable if enabled, resolves file:// references
+// unless the field has the literal option, and then applies the field's transformations. It returns
+// the value after expansion, and the value to apply.
+func (b *binder) resolveEnv(value string, tags fieldTags) (expanded, resolved string, err error) {
+	if b.expander != nil {
+		if value, err = b.expander.expand(value); err != nil {
+			return value, value, err
+		}
+	}
+	resolved = value
+	if !tags.hasOption(optLiteral) && strings.HasPrefix(value, fileRefPrefix) {
+		if resolved, err = readEnvFileRef(b.files, value); err != nil {
+			return value, resolved, err
+		}
+	}
+	for _, name := range tags.envTransform {
+		if resolved, err = b.types.lookupTransform(name)(resolved); err != nil {
+			return value, resolved, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return value, resolved, nil
+}
+
+// checkRoundTrip verifies that the non-zero value of a textUnmarshalledFlag field can be parsed
+// back from its string representation. The text is parsed into a copy of the value rather than a
+// zero one, so that types which carry state besides the value itself, e.g. the allowed values of
+// an enum, can be checked.
+func checkRoundTrip(value reflect.Value, opts fieldOpts) error {
+	if value.IsZero() || skipsRoundTrip(value) {
+		return nil
+	}
+	text := value.Addr().Interface().(textUnmarshalledFlag).String()
+	parsed := reflect.New(value.Type())
+	parsed.Elem().Set(value)
+	return compareRoundTrip(value, parsed.Elem(), text, opts, func(s string) error {
+		return parsed.Interface().(textUnmarshalledFlag).UnmarshalText([]byte(s))
+	})
+}
+
+// checkValueRoundTrip is like checkRoundTrip, but for pflag.Value fields. Their text is parsed into
+// a zero value, as Set on a copy could change what the copy shares with the field, e.g. through a
+// pointer. Types that cannot be set without such state opt out via CmdSkipRoundTrip.
+func checkValueRoundTrip(value reflect.Value, opts fieldOpts) (err error) {
+	if value.IsZero() || skipsRoundTrip(value) {
+		return nil
+	}
+	text := value.Addr().Interface().(pflag.Value).String()
+	parsed := reflect.New(value.Type())
+	defer func() {
+		if r := recover(); r != nil {
+			reason := fmt.Sprint(r)
+			if opts.secret {
+				reason = redacted
+			}
+			err = fmt.Errorf("cannot set a zero value to its own string representation %s: %s; "+
+				"implement CmdSkipRoundTrip to skip this check", traceValue(opts, text), reason)
+		}
+	}()
+	return compareRoundTrip(value, parsed.Elem(), text, opts, parsed.Interface().(pflag.Value).Set)
+}
+
+// compareRoundTrip parses text via parse into parsed, and compares the result with value.
+func compareRoundTrip(value, parsed reflect.Value, text string, opts fieldOpts, parse func(string) error) error {
+	if err := parse(text); err != nil {
+		if opts.secret {
+			return fmt.Errorf("cannot parse its own string representation %s", redacted)
+		}
+		return fmt.Errorf("cannot parse its own string representation %q: %w", text, err)
+	}
+	if !reflect.DeepEqual(parsed.Interface(), value.Interface()) {
+		return fmt.Errorf("string representation %s does not parse back to the same value", traceValue(opts, text))
+	}
+	return nil
+}
+
+// skipsRoundTrip reports whether the type of value opts out of the round trip check of defaults.
+func skipsRoundTrip(value reflect.Value) bool {
+	_, ok := value.Addr().Interface().(roundTripSkipper)
+	return ok
+}
+
+// isFlagValue reports whether a pointer to a struct is bound as a single flag.
+func isFlagValue(in any) bool {
+	switch in.(type) {
+	case *net.IPNet, *netip.Addr, *netip.Prefix, *netip.AddrPort, *url.URL, pflag.Value, textUnmarshalledFlag:
+		return true
+	default:
+		return false
+	}
+}
+
+// colorize wraps s in an ANSI color escape sequence, unless colors are disabled.
+func colorize(ansiColor, s string) string {
+	if !colors {
+		return s
+	}
+	return "\033[" + ansiColor + "m" + s + "\033[0m"
+}
+
+// traceValue formats a value for tracing, unless it belongs to a secret flag.
+func traceValue(opts fieldOpts, value string) string {
+	if opts.secret {
+		return redacted
+	}
+	return strconv.Quote(value)
 }
 
 type fieldOpts struct {
 	persistent bool
 	required   bool
+	secret     bool
+	hidden     bool
+	helpGroup  string // see annotationHelpGroup
 }
 
 func (opts fieldOpts) Or(other fieldOpts) (result fieldOpts) {
 	result.persistent = opts.persistent || other.persistent
 	result.required = opts.required || other.required
+	result.secret = opts.secret || other.secret
+	result.hidden = opts.hidden || other.hidden
+	result.helpGroup = cmp.Or(opts.helpGroup, other.helpGroup)
 	return
 }
 
 type fieldTags struct {
-	opts     []string
-	encoding string
-	name     string
-	abbrev   string
-	env      string
-	usage    string
+	opts          []string
+	encoding      string
+	name          string
+	abbrev        string
+	env           string
+	envAliases    []string // further environment variables from the env tag, e.g. "FOO|BAR"
+	envDeprecated []string // former environment variables, read with a warning if none other is set
+	envSeparator  string   // splits slice values of environment variables instead of CSV
+	defValue      string   // default of the default tag, see BindConfig
+	envDefault    string   // default that replaces the field's value, see BindConfig
+	envMap        string   // reads map entries from variables with the field's variable as prefix
+	envTransform  []string // names of transformations applied to values of environment variables
+	deprecated    string   // notice for the flag's deprecation, e.g. "use --bar instead"
+	choices       []string // allowed values of the flag
+	min, max      string   // bounds of numeric flags
+	pattern       string   // regular expression that values of string flags must match
+	groups        []string // groups of the flag, e.g. "auth,mutex"
+	envPrefix     string   // replaces the field's part of the env prefix of a nested struct
+	flagAliases   []string // names of hidden flags that set the same value, e.g. former names
+	complete      string   // shell completion of paths, e.g. "file,yaml,yml" or "dir"
+	helpGroup     string   // title of the flag's section in help texts
+	arg           string   // index of the positional argument bound instead of a flag
+	expand        string   // "path" to expand ~ and environment variables in values
+	schemes       []string // allowed schemes of URLs
+	usage         string
+}
+
+// fieldMeta is what can be derived from a struct field independently of where its struct is bound.
+// It is computed once per type, as commands commonly share configuration types.
+type fieldMeta struct {
+	field reflect.StructField
+	tags  fieldTags // tags without prefixes applied
+	slug  string    // kebab-case of the field name
+	snake string    // screaming snake case of the field name
+	cmd   string    // use line of a sub-command declared via New, not bound to flags
+}
+
+// typeCache maps struct types to their []fieldMeta.
+var typeCache sync.Map
+
+func typeFields(t reflect.Type) []fieldMeta {
+	if metas, ok := typeCache.Load(t); ok {
+		return metas.([]fieldMeta)
+	}
+	metas := make([]fieldMeta, t.NumField())
+	for i := range metas {
+		field := t.Field(i)
+		meta := &metas[i]
+		meta.field = field
+		meta.tags.opts = strings.Split(field.Tag.Get("flag"), ",")
+		meta.tags.encoding = field.Tag.Get("encoding")
+		meta.tags.name, meta.tags.abbrev, _ = strings.Cut(field.Tag.Get("param"), ",")
+		meta.tags.env = field.Tag.Get("env")
+		meta.tags.envSeparator = field.Tag.Get("envSeparator")
+		meta.tags.envMap = field.Tag.Get("envMap")
+		meta.tags.envDefault = field.Tag.Get("envDefault")
+		meta.tags.defValue = field.Tag.Get("default")
+		if transform := field.Tag.Get("envTransform"); transform != "" {
+			meta.tags.envTransform = strings.Split(transform, ",")
+		}
+		if deprecated := field.Tag.Get("envDeprecated"); deprecated != "" {
+			meta.tags.envDeprecated = strings.Split(deprecated, "|")
+		}
+		meta.tags.deprecated = field.Tag.Get("deprecated")
+		meta.tags.min = field.Tag.Get("min")
+		meta.tags.max = field.Tag.Get("max")
+		meta.tags.pattern = field.Tag.Get("pattern")
+		meta.tags.envPrefix = field.Tag.Get("envPrefix")
+		meta.tags.complete = field.Tag.Get("complete")
+		meta.tags.helpGroup = field.Tag.Get("helpgroup")
+		meta.tags.arg = field.Tag.Get("arg")
+		meta.tags.expand = field.Tag.Get("expand")
+		if schemes := field.Tag.Get("schemes"); schemes != "" {
+			meta.tags.schemes = strings.Split(schemes, ",")
+		}
+		if aliases := field.Tag.Get("aliases"); aliases != "" {
+			meta.tags.flagAliases = strings.Split(aliases, ",")
+		}
+		if groups := field.Tag.Get("group"); groups != "" {
+			meta.tags.groups = strings.Split(groups, "|")
+		}
+		if choices := field.Tag.Get("choices"); choices != "" {
+			meta.tags.choices = strings.Split(choices, ",")
+		}
+		meta.tags.usage = field.Tag.Get("usage")
+		meta.slug = Slug(field.Name, '-')
+		meta.snake = ScreamingSnake(field.Name)
+		meta.cmd = field.Tag.Get("cmd")
+	}
+	actual, _ := typeCache.LoadOrStore(t, metas)
+	return actual.([]fieldMeta)
+}
+
+// checkEnvPrefixTag verifies the envPrefix tag of a nested struct or sub-command.
+func checkEnvPrefixTag(meta fieldMeta) error {
+	switch prefix := meta.tags.envPrefix; {
+	case meta.tags.env != "":
+		return fmt.Errorf("env and envPrefix of %s are mutually exclusive", meta.field.Name)
+	case strings.ToUpper(prefix) != prefix:
+		return fmt.Errorf("envPrefix %q must be all uppercase", prefix)
+	case strings.HasPrefix(prefix, "_") || strings.HasSuffix(prefix, "_"):
+		return fmt.Errorf("envPrefix %q must not start or end with an underscore", prefix)
+	}
+	return nil
 }
 
-func getFieldTags(paramPrefix, envPrefix string, field reflect.StructField) (tags fieldTags) {
-	tags.opts = strings.Split(field.Tag.Get("flag"), ",")
-	tags.encoding = field.Tag.Get("encoding")
-	tags.name, tags.abbrev, _ = strings.Cut(field.Tag.Get("param"), ",")
-	tags.env = field.Tag.Get("env")
-	tags.usage = field.Tag.Get("usage")
+func getFieldTags(paramPrefix, envPrefix string, meta fieldMeta) (tags fieldTags, err error) {
+	tags = meta.tags
 
 	if len(tags.name) == 1 {
 		if tags.abbrev != "" {
-			panic(fmt.Sprintf("param %q must be at least two characters", tags.name))
+			return tags, fmt.Errorf("param %q must be at least two characters", tags.name)
 		}
 		tags.abbrev = tags.name
 		tags.name = ""
 	}
 	if tags.name == "" {
-		tags.name = paramPrefix + slug(field.Name, '-')
+		tags.name = paramPrefix + meta.slug
 	} else {
 		tags.name = paramPrefix + tags.name
 	}
 
 	if len(tags.abbrev) > 1 {
-		panic(fmt.Sprintf("abbreviation %q for %q must be a single character", tags.abbrev, tags.name))
+		return tags, fmt.Errorf("abbreviation %q for %q must be a single character", tags.abbrev, tags.name)
 	}
 
 	if tags.env == "" {
-		tags.env = envPrefix + screamingSnake(field.Name)
+		tags.env = envPrefix + meta.snake
 	} else if tags.env != strings.ToUpper(tags.env) {
-		panic(fmt.Sprintf("env tag %q for %q must be uppercase", tags.env, tags.name))
+		return tags, fmt.Errorf("env tag %q for %q must be uppercase", tags.env, tags.name)
+	} else if names := strings.Split(tags.env, "|"); len(names) > 1 {
+		if slices.Contains(names, "") || slices.Contains(names, "-") {
+			return tags, fmt.Errorf("env tag %q for %q must not contain empty aliases or \"-\"", tags.env, tags.name)
+		}
+		tags.env, tags.envAliases = names[0], names[1:]
+	}
+	for _, old := range tags.envDeprecated {
+		if old == "" || old != strings.ToUpper(old) {
+			return tags, fmt.Errorf("envDeprecated tag %q for %q must be uppercase", old, tags.name)
+		}
+	}
+
+	if tags.flagAliases != nil {
+		aliases := make([]string, len(tags.flagAliases))
+		for i, alias := range tags.flagAliases {
+			if len(alias) < 2 || alias != Slug(alias, '-') {
+				return tags, fmt.Errorf("alias %q for %q must be at least two characters of kebab-case", alias, tags.name)
+			}
+			aliases[i] = paramPrefix + alias
+			if meta.tags.env == "" { // derived names get derived aliases, custom ones do not
+				envAlias := envPrefix + strings.ToUpper(strings.ReplaceAll(alias, "-", "_"))
+				tags.envAliases = append(slices.Clip(tags.envAliases), envAlias)
+			}
+		}
+		tags.flagAliases = aliases
 	}
 
 	return
@@ -297,6 +1020,21 @@ func (ft fieldTags) hasOption(name string) bool {
 func (ft fieldTags) Opts() (opts fieldOpts) {
 	opts.persistent = ft.hasOption(optPersistent)
 	opts.required = ft.hasOption(optRequired)
+	opts.secret = ft.hasOption(optSecret)
+	opts.hidden = ft.hasOption(optHidden)
+	opts.helpGroup = ft.helpGroup
+	return
+}
+
+// UnknownOpts returns all options of the flag tag that nicecmd does not know about.
+func (ft fieldTags) UnknownOpts() (unknown []string) {
+	for _, opt := range ft.opts {
+		switch opt {
+		case "", optPersistent, optRequired, optSecret, optHidden, optInline, optNegatable, optExists, optLiteral:
+		default:
+			unknown = append(unknown, opt)
+		}
+	}
 	return
 }
 
@@ -304,6 +1042,17 @@ func (ft fieldTags) HasEnv() bool {
 	return ft.env != "-"
 }
 
+// EnvNames returns the environment variables bound to the field, in order of precedence.
+func (ft fieldTags) EnvNames() []string {
+	return append([]string{ft.env}, ft.envAliases...)
+}
+
+// roundTripSkipper is implemented by custom flag types whose defaults are not checked for parsing
+// back from their string representation, e.g. because Set relies on state of the value.
+type roundTripSkipper interface {
+	CmdSkipRoundTrip()
+}
+
 type textUnmarshalledFlag interface {
 	encoding.TextUnmarshaler
 	String() string
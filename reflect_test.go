@@ -3,11 +3,15 @@ package nicecmd
 import (
 	"bufio"
 	"bytes"
+	"errors"
+	"fmt"
 	"github.com/spf13/cobra"
+	"io"
 	"net"
 	"os"
 	"reflect"
 	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
@@ -180,15 +184,45 @@ func TestBindConfig_InvalidConfigTags(t *testing.T) {
 		{name: "raw string slice with env", panic: `requires env:"-"`, conf: &struct {
 			String []string `encoding:"raw"`
 		}{}},
-		{name: "counted int with env", panic: `requires env:"-"`, conf: &struct {
-			Int int `encoding:"count"`
-		}{}},
 		{name: "bad type", panic: "unsupported field type *nicecmd.unsupported", conf: &struct {
 			Unsupported unsupported
 		}{}},
 		{name: "bad env name", panic: "must be uppercase", conf: &struct {
 			String string `env:"lowercase"`
 		}{}},
+		{name: "env separator for non-slice", panic: "requires a slice type", conf: &struct {
+			String string `envSeparator:":"`
+		}{}},
+		{name: "env map for non-map", panic: "requires a map type", conf: &struct {
+			String string `envMap:"keep"`
+		}{}},
+		{name: "bad env map", panic: `expected envMap:"keep" or envMap:"lower"`, conf: &struct {
+			Labels map[string]string `envMap:"upper"`
+		}{}},
+		{name: "bad deprecated env name", panic: "must be uppercase", conf: &struct {
+			String string `envDeprecated:"old"`
+		}{}},
+		{name: "bad env default", panic: `envDefault "x" for "int"`, conf: &struct {
+			Int int `envDefault:"x"`
+		}{}},
+		{name: "bad default", panic: `default "x" for "int"`, conf: &struct {
+			Int int `default:"x"`
+		}{}},
+		{name: "default differs", panic: `default "1" for "int" differs from the value 2 of the struct`, conf: &struct {
+			Int int `default:"1"`
+		}{Int: 2}},
+		{name: "default and env default", panic: `default and envDefault for "int" are mutually exclusive`, conf: &struct {
+			Int int `default:"1" envDefault:"1"`
+		}{}},
+		{name: "duplicate env", panic: "environment variable TEST_A is already bound to Nested.B", conf: &struct {
+			Nested struct {
+				B string `env:"TEST_A"`
+			}
+			A string
+		}{}},
+		{name: "bad env alias", panic: "must not contain empty aliases", conf: &struct {
+			String string `env:"FOO||BAR"`
+		}{}},
 		{name: "bad abbreviation", panic: "must be a single character", conf: &struct {
 			String string `param:"foo,bar"`
 		}{}},
@@ -280,3 +314,696 @@ func TestBindConfig_BadEnvironment(t *testing.T) {
 		t.Errorf("expected BindConfig to print environment variable error, but got output: %v", out)
 	}
 }
+
+func TestBindConfig_EnvAliases(t *testing.T) {
+	type AliasConfig struct {
+		URL string `env:"DATABASE_URL|TEST_DB_URL"`
+	}
+	bind := func(env map[string]string) (*cobra.Command, AliasConfig) {
+		var cfg AliasConfig
+		cmd := &cobra.Command{}
+		if err := TryBindConfig("TEST", cmd, &cfg, WithLookupEnv(func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		})); err != nil {
+			t.Fatalf("bind: %v", err)
+		}
+		return cmd, cfg
+	}
+
+	cmd, cfg := bind(map[string]string{"DATABASE_URL": "", "TEST_DB_URL": "second"})
+	if cfg.URL != "second" {
+		t.Errorf("expected alias to be used when the first variable is empty, got %q", cfg.URL)
+	}
+	if usage := cmd.Flags().Lookup("url").Usage; !strings.Contains(usage, `env TEST_DB_URL="second"`) {
+		t.Errorf("expected usage to name the applied alias, got %q", usage)
+	}
+	if fields := Fields(cmd); fields[0].Env != "DATABASE_URL" || !slices.Equal(fields[0].EnvAliases, []string{"TEST_DB_URL"}) {
+		t.Errorf("expected FieldInfo to list all names, got %+v", fields[0])
+	}
+
+	_, cfg = bind(map[string]string{"DATABASE_URL": "first", "TEST_DB_URL": "second"})
+	if cfg.URL != "first" {
+		t.Errorf("expected first variable to win, got %q", cfg.URL)
+	}
+
+	cmd, _ = bind(nil)
+	if usage := cmd.Flags().Lookup("url").Usage; !strings.Contains(usage, "env DATABASE_URL, TEST_DB_URL") {
+		t.Errorf("expected usage to list all aliases, got %q", usage)
+	}
+}
+
+func TestBindConfig_EnvSeparator(t *testing.T) {
+	type SepConfig struct {
+		Path  []string        `envSeparator:":"`
+		Raw   []string        `encoding:"raw" envSeparator:";"`
+		Delay []time.Duration `envSeparator:" "`
+	}
+	cfg := SepConfig{Path: []string{"/bin"}}
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{
+		"TEST_PATH=/usr/bin:/opt/a,b",
+		"TEST_RAW=x,y;z",
+		"TEST_DELAY=1s 2m",
+	}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := SepConfig{
+		Path:  []string{"/usr/bin", "/opt/a,b"},
+		Raw:   []string{"x,y", "z"},
+		Delay: []time.Duration{time.Second, 2 * time.Minute},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected values split by separator, got %+v", cfg)
+	}
+	if err := cmd.ParseFlags([]string{"--raw", "cli", "--raw", "flag"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !slices.Equal(cfg.Raw, []string{"cli", "flag"}) {
+		t.Errorf("expected flags to replace the value of the environment variable, got %v", cfg.Raw)
+	}
+}
+
+func TestBindConfig_IndexedEnv(t *testing.T) {
+	type IndexedConfig struct {
+		Peers []string
+		Ports []int `env:"PORTS|TEST_PORTS"`
+		Name  string
+	}
+	var cfg IndexedConfig
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{
+		"TEST_PEERS_0=a,b",
+		"TEST_PEERS_1=c",
+		"TEST_PEERS_3=ignored after gap",
+		"TEST_PORTS_0=80",
+		"TEST_PORTS_1=443",
+		"TEST_NAME_0=ignored for non-slices",
+	}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := IndexedConfig{Peers: []string{"a,b", "c"}, Ports: []int{80, 443}}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected indexed variables to be applied, got %+v", cfg)
+	}
+	if usage := cmd.Flags().Lookup("ports").Usage; !strings.Contains(usage, `env TEST_PORTS_*="80,443"`) {
+		t.Errorf("expected usage to show indexed variables, got %q", usage)
+	}
+
+	cfg = IndexedConfig{}
+	err = TryBindConfig("TEST", &cobra.Command{}, &cfg, WithEnviron([]string{"TEST_PEERS=x", "TEST_PEERS_0=y", "TEST_PORTS_0=z"}))
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_PORTS_*" {
+		t.Errorf("expected *EnvError for invalid indexed value, got %v", err)
+	}
+	if !slices.Equal(cfg.Peers, []string{"x"}) {
+		t.Errorf("expected plain variable to take precedence, got %v", cfg.Peers)
+	}
+}
+
+func TestBindConfig_EnvMap(t *testing.T) {
+	type MapConfig struct {
+		Labels map[string]string `envMap:"lower"`
+		Limits map[string]int    `envMap:"keep"`
+	}
+	var cfg MapConfig
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{
+		"TEST_LABELS_TIER=web",
+		"TEST_LABELS_ZONES=a,b",
+		"TEST_LABELS_EMPTY=",
+		"TEST_LIMITS_CPU=2",
+		"TEST_LIMITS=",
+	}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := MapConfig{
+		Labels: map[string]string{"tier": "web", "zones": "a,b"},
+		Limits: map[string]int{"CPU": 2},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected map entries from variables, got %+v", cfg)
+	}
+
+	cfg = MapConfig{}
+	err = TryBindConfig("TEST", &cobra.Command{}, &cfg, WithEnviron([]string{"TEST_LABELS=x=1", "TEST_LABELS_Y=2"}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"x": "1"}) {
+		t.Errorf("expected single variable to take precedence, got %v", cfg.Labels)
+	}
+}
+
+func TestBindConfig_EnvDeprecated(t *testing.T) {
+	type DeprecatedConfig struct {
+		URL string `envDeprecated:"OLD_URL|OLDER_URL"`
+	}
+	var warnings []string
+	WarningHandler = func(cmd *cobra.Command, msg string) {
+		warnings = append(warnings, msg)
+	}
+	defer func() { WarningHandler = nil }()
+
+	var cfg DeprecatedConfig
+	if err := TryBindConfig("TEST", &cobra.Command{}, &cfg, WithEnviron([]string{"OLDER_URL=older"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := "environment variable OLDER_URL is deprecated, use TEST_URL instead"
+	if cfg.URL != "older" || len(warnings) != 1 || warnings[0] != want {
+		t.Errorf("expected deprecated variable to be read with a warning, got %q, %v", cfg.URL, warnings)
+	}
+
+	warnings = nil
+	cfg = DeprecatedConfig{}
+	if err := TryBindConfig("TEST", &cobra.Command{}, &cfg, WithEnviron([]string{"OLD_URL=old", "TEST_URL=new"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.URL != "new" || len(warnings) != 0 {
+		t.Errorf("expected current variable to take precedence without warning, got %q, %v", cfg.URL, warnings)
+	}
+}
+
+func TestBindConfig_EnvDefault(t *testing.T) {
+	type DefaultConfig struct {
+		Port  int           `envDefault:"8080"`
+		Tags  []string      `envDefault:"a,b"`
+		Key   []byte        `encoding:"hex" envDefault:"cafe"`
+		Delay time.Duration `envDefault:"1s"`
+	}
+	var cfg DefaultConfig
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_DELAY=2s"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := DefaultConfig{Port: 8080, Tags: []string{"a", "b"}, Key: []byte{0xca, 0xfe}, Delay: 2 * time.Second}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected defaults from tags below the environment, got %+v", cfg)
+	}
+	if port := cmd.Flags().Lookup("port"); port.DefValue != "8080" || port.Changed {
+		t.Errorf("expected tag to set the flag's default, got %q", port.DefValue)
+	}
+	if err := cmd.ParseFlags([]string{"--tags", "c"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if !slices.Equal(cfg.Tags, []string{"c"}) {
+		t.Errorf("expected flag to replace the default, got %v", cfg.Tags)
+	}
+}
+
+func TestBindConfig_Default(t *testing.T) {
+	type DefaultConfig struct {
+		Port  int           `default:"8080"`
+		Name  string        `default:"foo"`
+		Delay time.Duration `default:"1s"`
+	}
+	cfg := DefaultConfig{Name: "foo"}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_DELAY=2s"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := DefaultConfig{Port: 8080, Name: "foo", Delay: 2 * time.Second}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected defaults from tags below the environment, got %+v", cfg)
+	}
+	if port := cmd.Flags().Lookup("port"); port.DefValue != "8080" || port.Changed {
+		t.Errorf("expected tag to set the flag's default, got %q", port.DefValue)
+	}
+}
+
+func TestBindConfig_Hidden(t *testing.T) {
+	type HiddenConfig struct {
+		Name   string
+		Tuning struct {
+			Batch int
+		} `flag:"hidden"`
+	}
+	var cfg HiddenConfig
+	cmd := &cobra.Command{Use: "test"}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_TUNING_BATCH=10"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Tuning.Batch != 10 {
+		t.Errorf("expected environment to apply to hidden flag, got %d", cfg.Tuning.Batch)
+	}
+	if err := cmd.ParseFlags([]string{"--tuning-batch", "20"}); err != nil || cfg.Tuning.Batch != 20 {
+		t.Errorf("expected hidden flag to be settable, got %d (%v)", cfg.Tuning.Batch, err)
+	}
+	if usage := cmd.Flags().FlagUsages(); strings.Contains(usage, "tuning-batch") || !strings.Contains(usage, "--name") {
+		t.Errorf("expected hidden flag to be left out of usage, got:\n%s", usage)
+	}
+}
+
+func TestBindConfig_Deprecated(t *testing.T) {
+	type DeprecatedConfig struct {
+		Addr string `param:"addr,a" deprecated:"use --listen instead"`
+	}
+	var cfg DeprecatedConfig
+	cmd := &cobra.Command{Use: "test"}
+	var stderr strings.Builder
+	cmd.SetErr(&stderr)
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_ADDR=:80"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Addr != ":80" {
+		t.Errorf("expected environment to apply to deprecated flag, got %q", cfg.Addr)
+	}
+	cmd.Flags().SetOutput(&stderr)
+	if err := cmd.ParseFlags([]string{"-a", ":81"}); err != nil || cfg.Addr != ":81" {
+		t.Errorf("expected deprecated flag to be settable, got %q (%v)", cfg.Addr, err)
+	}
+	if !strings.Contains(stderr.String(), "use --listen instead") {
+		t.Errorf("expected deprecation notice, got %q", stderr.String())
+	}
+	if fields := Fields(cmd); len(fields) != 1 || fields[0].Deprecated != "use --listen instead" {
+		t.Errorf("expected notice in field info, got %+v", fields)
+	}
+}
+
+func TestBindConfig_Inline(t *testing.T) {
+	type TLSConfig struct {
+		CertFile string
+	}
+	type InlineConfig struct {
+		Server struct {
+			TLS  TLSConfig `flag:"inline"`
+			Port int
+		}
+	}
+	var cfg InlineConfig
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_SERVER_CERT_FILE=cert.pem"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cmd.Flags().Lookup("server-cert-file") == nil || cmd.Flags().Lookup("server-port") == nil {
+		t.Error("expected inlined fields to keep the prefix of the enclosing struct only")
+	}
+	if cfg.Server.TLS.CertFile != "cert.pem" {
+		t.Errorf("expected environment variable without the prefix of the inlined struct, got %+v", cfg)
+	}
+
+	var bindErr *BindError
+	err := TryBindConfig("TEST", &cobra.Command{}, &struct {
+		Port int `flag:"inline"`
+	}{}, WithEnviron(nil))
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected *BindError for inline on a field that is not a struct, got %v", err)
+	}
+}
+
+func TestBindConfig_EnvPrefixTag(t *testing.T) {
+	type PrefixConfig struct {
+		DatabaseConnection struct {
+			Host string
+		} `param:"db" envPrefix:"DATABASE"`
+	}
+	var cfg PrefixConfig
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_DATABASE_HOST=db"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cmd.Flags().Lookup("db-host") == nil || cfg.DatabaseConnection.Host != "db" {
+		t.Errorf("expected --db-host and TEST_DATABASE_HOST, got %+v", cfg)
+	}
+
+	var bindErr *BindError
+	for name, conf := range map[string]any{
+		"not a struct": &struct {
+			Host string `envPrefix:"DB"`
+		}{},
+		"lowercase": &struct {
+			DB struct{ Host string } `envPrefix:"db"`
+		}{},
+		"underscore": &struct {
+			DB struct{ Host string } `envPrefix:"DB_"`
+		}{},
+		"with env": &struct {
+			DB struct{ Host string } `env:"DB" envPrefix:"DB"`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("%s: expected *BindError, got %v", name, err)
+		}
+	}
+}
+
+func TestBindConfig_FlagAliases(t *testing.T) {
+	type AliasConfig struct {
+		Server struct {
+			ListenAddr string `flag:"required" aliases:"addr,bind-addr"`
+		}
+		Verbose bool `aliases:"debug"`
+	}
+	bind := func(environ []string, args ...string) (AliasConfig, error) {
+		var cfg AliasConfig
+		cmd, err := TryCommand("TEST", RunFuncs[AliasConfig]{Run: func(c AliasConfig, cmd *cobra.Command, args []string) error {
+			cfg = c
+			return nil
+		}}, cobra.Command{Use: "test"}, AliasConfig{}, WithEnviron(environ))
+		if err != nil {
+			return cfg, err
+		}
+		cmd.SetArgs(args)
+		cmd.SetOut(io.Discard)
+		cmd.SetErr(io.Discard)
+		return cfg, cmd.Execute()
+	}
+
+	cfg, err := bind(nil, "--server-bind-addr", ":80", "--debug")
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if cfg.Server.ListenAddr != ":80" || !cfg.Verbose {
+		t.Errorf("expected aliases to set the fields and satisfy required flags, got %+v", cfg)
+	}
+	if cfg, err = bind([]string{"TEST_SERVER_ADDR=:81"}); err != nil || cfg.Server.ListenAddr != ":81" {
+		t.Errorf("expected environment variable of the alias to apply, got %+v (%v)", cfg, err)
+	}
+
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &AliasConfig{}, WithEnviron(nil)); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if usage := cmd.Flags().FlagUsages(); strings.Contains(usage, "bind-addr") || !strings.Contains(usage, "TEST_SERVER_ADDR") {
+		t.Errorf("expected hidden aliases with their variables listed, got:\n%s", usage)
+	}
+
+	var bindErr *BindError
+	for name, conf := range map[string]any{
+		"taken": &struct {
+			Foo string `aliases:"bar"`
+			Bar string
+		}{},
+		"not kebab-case": &struct {
+			Foo string `aliases:"Bar"`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("%s: expected *BindError, got %v", name, err)
+		}
+	}
+}
+
+func TestBindConfig_Debug(t *testing.T) {
+	Debug = true
+	defer func() { Debug = false }()
+	if err := os.Setenv("NICECMD_DEBUG_FOO", "foo"); err != nil {
+		t.Errorf("setenv: %v", err)
+		return
+	}
+	defer func() { _ = os.Unsetenv("NICECMD_DEBUG_FOO") }()
+
+	var conf struct {
+		Foo    string `param:"f"`
+		Nested struct {
+			Bar int
+		}
+		Baz bool `env:"-"`
+	}
+	cmd := &cobra.Command{}
+	buf := &bytes.Buffer{}
+	cmd.SetErr(buf)
+	BindConfig("NICECMD_DEBUG", cmd, &conf)
+
+	out := buf.String()
+	for _, want := range []string{
+		"nicecmd: Foo: string with tags `param:\"f\"` bound to flag --foo (shorthand \"f\"",
+		`nicecmd: Foo: environment variable NICECMD_DEBUG_FOO="foo" applied to --foo`,
+		"nicecmd: Nested: nested struct with tags ``, flag prefix --nested-, env prefix NICECMD_DEBUG_NESTED_",
+		`nicecmd: Nested.Bar: environment variable NICECMD_DEBUG_NESTED_BAR is not set, keeping default "0"`,
+		`nicecmd: Baz: environment variable disabled via env:"-"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected trace to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestTryBindConfig(t *testing.T) {
+	var bindErr *BindError
+	err := TryBindConfig("TEST", &cobra.Command{}, &struct {
+		Nested struct {
+			Int int `encoding:"foo"`
+		}
+	}{})
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected BindError, got %v", err)
+	} else if bindErr.Field != "Nested.Int" {
+		t.Errorf("expected error for field Nested.Int, got %q", bindErr.Field)
+	}
+
+	err = TryBindConfig("TEST", &cobra.Command{}, &struct {
+		Foo string `param:"foo"`
+		Bar string `param:"foo"`
+	}{})
+	if !errors.As(err, &bindErr) || !strings.Contains(err.Error(), `flag "foo" is already defined`) {
+		t.Errorf("expected BindError for duplicate flag, got %v", err)
+	}
+
+	envs := map[string]string{"NICECMD_TRY_BAD1": "one", "NICECMD_TRY_BAD2": "two"}
+	for k, v := range envs {
+		if err := os.Setenv(k, v); err != nil {
+			t.Errorf("setenv: %v", err)
+			return
+		}
+	}
+	defer func() {
+		for k := range envs {
+			_ = os.Unsetenv(k)
+		}
+	}()
+	cmd := &cobra.Command{}
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	err = TryBindConfig("NICECMD_TRY", cmd, &struct {
+		Bad1 int
+		Bad2 int
+	}{})
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "NICECMD_TRY_BAD1" || envErr.Value != "one" {
+		t.Errorf("expected EnvError for NICECMD_TRY_BAD1, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "NICECMD_TRY_BAD2") {
+		t.Errorf("expected error to mention NICECMD_TRY_BAD2, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected TryBindConfig not to print, got %q", buf.String())
+	}
+}
+
+func TestBindConfig_Shadowing(t *testing.T) {
+	parent := &cobra.Command{Use: "parent"}
+	parent.PersistentFlags().Bool("verbose", false, "")
+	child := &cobra.Command{Use: "child"}
+	parent.AddCommand(child)
+
+	err := TryBindConfig("TEST", child, &struct{ Verbose bool }{})
+	if err == nil || !strings.Contains(err.Error(), `flag "verbose" shadows the persistent flag of "parent"`) {
+		t.Errorf("expected shadowing error, got %v", err)
+	}
+}
+
+type brokenValue struct{ val string }
+
+func (b *brokenValue) UnmarshalText(text []byte) error { b.val = string(text); return nil }
+func (b *brokenValue) String() string                  { return strings.ToUpper(b.val) }
+func (b *brokenValue) CmdTypeDesc() string             { return "broken" }
+
+func TestBindConfig_DefaultRoundTrip(t *testing.T) {
+	conf := struct {
+		Nice   niceValue
+		Broken brokenValue
+	}{
+		Nice:   niceValue{val: "nice"},
+		Broken: brokenValue{val: "foo"},
+	}
+	err := TryBindConfig("TEST", &cobra.Command{}, &conf)
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) || bindErr.Field != "Broken" {
+		t.Errorf("expected BindError for field Broken, got %v", err)
+	} else if !strings.Contains(err.Error(), `string representation "FOO" does not parse back`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	conf.Broken = brokenValue{}
+	if err := TryBindConfig("TEST", &cobra.Command{}, &conf); err != nil {
+		t.Errorf("expected zero value to be accepted, got %v", err)
+	}
+}
+
+// brokenFlag is like brokenValue, but a pflag.Value.
+type brokenFlag struct{ val string }
+
+func (b *brokenFlag) Set(s string) error { b.val = s; return nil }
+func (b *brokenFlag) String() string     { return strings.ToUpper(b.val) }
+func (b *brokenFlag) Type() string       { return "broken" }
+
+// nilFlag is a pflag.Value backed by a pointer, without opting out of the round trip check.
+type nilFlag struct{ p *string }
+
+func (v nilFlag) Set(s string) error { *v.p = s; return nil }
+func (v nilFlag) String() string     { return *v.p }
+func (v nilFlag) Type() string       { return "nil" }
+
+func TestBindConfig_DefaultRoundTripValue(t *testing.T) {
+	target := "foo"
+	for _, tc := range []struct {
+		conf any
+		want string
+	}{
+		{&struct{ Broken brokenFlag }{brokenFlag{val: "foo"}}, `string representation "FOO" does not parse back`},
+		{&struct{ Nil nilFlag }{nilFlag{p: &target}}, `cannot set a zero value to its own string representation "foo"`},
+	} {
+		err := TryBindConfig("TEST", &cobra.Command{}, tc.conf)
+		var bindErr *BindError
+		if !errors.As(err, &bindErr) || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("expected BindError containing %q, got %v", tc.want, err)
+		}
+	}
+	if err := TryBindConfig("TEST", &cobra.Command{}, &struct{ Broken brokenFlag }{}); err != nil {
+		t.Errorf("expected zero value to be accepted, got %v", err)
+	}
+}
+
+// ptrValue is a pflag.Value backed by a pointer, which a zero value does not have, so that it opts
+// out of the round trip check.
+type ptrValue struct{ p *string }
+
+func (v ptrValue) Set(s string) error { *v.p = s; return nil }
+func (v ptrValue) String() string     { return *v.p }
+func (v ptrValue) Type() string       { return "ptr" }
+func (v ptrValue) CmdSkipRoundTrip()  {}
+
+// enumValue is a pflag.Value that only accepts the values it carries, so that it opts out of the
+// round trip check.
+type enumValue struct {
+	value   string
+	allowed []string
+}
+
+func (e *enumValue) Set(s string) error {
+	if !slices.Contains(e.allowed, s) {
+		return fmt.Errorf("must be one of %s", strings.Join(e.allowed, ", "))
+	}
+	e.value = s
+	return nil
+}
+func (e *enumValue) String() string    { return e.value }
+func (e *enumValue) Type() string      { return "enum" }
+func (e *enumValue) CmdSkipRoundTrip() {}
+
+// enumText is like enumValue, but a textUnmarshalledFlag.
+type enumText struct {
+	value   string
+	allowed []string
+}
+
+func (e *enumText) UnmarshalText(text []byte) error {
+	if !slices.Contains(e.allowed, string(text)) {
+		return fmt.Errorf("must be one of %s", strings.Join(e.allowed, ", "))
+	}
+	e.value = string(text)
+	return nil
+}
+func (e *enumText) String() string      { return e.value }
+func (e *enumText) CmdTypeDesc() string { return "enum" }
+
+func TestBindConfig_DefaultRoundTripState(t *testing.T) {
+	target := "foo"
+	conf := struct {
+		Ptr      ptrValue
+		Format   enumValue
+		Encoding enumText
+	}{
+		Ptr:      ptrValue{p: &target},
+		Format:   enumValue{value: "json", allowed: []string{"json", "yaml"}},
+		Encoding: enumText{value: "utf8", allowed: []string{"ascii", "utf8"}},
+	}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &conf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--ptr", "bar", "--encoding", "ascii"}); err != nil || target != "bar" || conf.Encoding.value != "ascii" {
+		t.Errorf("expected flags to be set, got %q, %q, %v", target, conf.Encoding.value, err)
+	}
+}
+
+func TestBindConfig_DefaultRoundTripSecret(t *testing.T) {
+	conf := struct {
+		Token brokenValue `flag:"secret"`
+	}{Token: brokenValue{val: "hunter2"}}
+	err := TryBindConfig("TEST", &cobra.Command{}, &conf)
+	if err == nil || strings.Contains(strings.ToLower(err.Error()), "hunter2") || !strings.Contains(err.Error(), "<redacted>") {
+		t.Errorf("expected redacted round trip error, got %v", err)
+	}
+}
+
+func TestTypeFields_Cached(t *testing.T) {
+	type Conf struct {
+		LogLevel string `param:"level,l" flag:"persistent"`
+	}
+	metas := typeFields(reflect.TypeOf(Conf{}))
+	if len(metas) != 1 || metas[0].slug != "log-level" || metas[0].snake != "LOG_LEVEL" || metas[0].tags.abbrev != "l" {
+		t.Fatalf("unexpected field metadata: %+v", metas)
+	}
+	if again := typeFields(reflect.TypeOf(Conf{})); &again[0] != &metas[0] {
+		t.Error("expected metadata to be cached")
+	}
+}
+
+// largeConfigType returns a struct type with the given number of fields per level, nested depth
+// levels deep, mimicking large shared configuration modules.
+func largeConfigType(fields, depth int) reflect.Type {
+	sf := make([]reflect.StructField, 0, fields+1)
+	for i := 0; i < fields; i++ {
+		typ := reflect.TypeOf("")
+		if i%2 == 1 {
+			typ = reflect.TypeOf(0)
+		}
+		sf = append(sf, reflect.StructField{Name: fmt.Sprintf("Field%d", i), Type: typ})
+	}
+	if depth > 1 {
+		sf = append(sf, reflect.StructField{Name: "Nested", Type: largeConfigType(fields, depth-1)})
+	}
+	return reflect.StructOf(sf)
+}
+
+func BenchmarkBindConfig(b *testing.B) {
+	for _, bc := range []struct {
+		name          string
+		fields, depth int
+	}{
+		{"flat-500", 500, 1},
+		{"nested-5x100", 100, 5},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			typ := largeConfigType(bc.fields, bc.depth)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				cmd := &cobra.Command{}
+				if err := TryBindConfig("BENCH", cmd, reflect.New(typ).Interface()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBindConfig_Execute(b *testing.B) {
+	typ := largeConfigType(100, 5)
+	args := []string{"--field0", "foo", "--nested-nested-field1", "42"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		cmd := &cobra.Command{Use: "bench", Run: func(cmd *cobra.Command, args []string) {}}
+		if err := TryBindConfig("BENCH", cmd, reflect.New(typ).Interface()); err != nil {
+			b.Fatal(err)
+		}
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
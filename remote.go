@@ -0,0 +1,96 @@
+package nicecmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// remoteConfig fetches configuration files given as HTTPS URLs.
+type remoteConfig struct {
+	client *http.Client // nil for defaultConfigClient
+}
+
+// defaultConfigClient fetches remote configuration files unless WithConfigHTTPClient is given.
+var defaultConfigClient = &http.Client{Timeout: 10 * time.Second}
+
+// maxConfigSize limits the size of remote configuration files, which are read into memory.
+const maxConfigSize = 16 << 20
+
+// WithConfigHTTPClient makes the command fetch configuration files given as HTTPS URLs with
+// client, e.g. to set a different timeout, or TLS options such as a private certificate authority
+// or a client certificate. By default, a client with a timeout of 10 seconds is used.
+func WithConfigHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.config.remote.client = client
+	}
+}
+
+// isURL reports whether the path of a configuration file is a URL rather than a local path.
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://")
+}
+
+// redactURL returns rawURL without its password, query and fragment, which commonly hold
+// credentials, so that it can be shown in errors.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	u.RawQuery, u.ForceQuery, u.Fragment, u.RawFragment = "", false, "", ""
+	return u.Redacted()
+}
+
+// fetch downloads the configuration file at rawURL, and returns it along with its extension. The
+// extension is taken from the URL's path, or otherwise derived from the response's content type.
+func (r remoteConfig) fetch(ctx context.Context, rawURL string) ([]byte, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", errors.Unwrap(err) // without the URL
+	} else if u.Scheme != "https" {
+		return nil, "", fmt.Errorf("only https URLs are supported")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	client := r.client
+	if client == nil {
+		client = defaultConfigClient
+	}
+	resp, err := client.Do(req)
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactURL(rawURL)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigSize+1))
+	if err != nil {
+		return nil, "", err
+	} else if len(data) > maxConfigSize {
+		return nil, "", fmt.Errorf("larger than %d bytes", maxConfigSize)
+	}
+	ext := path.Ext(u.Path)
+	if ext == "" {
+		if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+			if exts, _ := mime.ExtensionsByType(mediaType); len(exts) != 0 {
+				ext = exts[0]
+			}
+		}
+	}
+	return data, ext, nil
+}
@@ -0,0 +1,70 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+// Resolve returns the configuration that the command selected by args would run with, without
+// invoking any hooks. Flags are parsed and validated just like Cobra would, and environment
+// variables are taken from env, or like for Clone if env is nil. Configuration files given via
+// flags of WithConfigFlag are applied as well.
+//
+// root must have been created by Command, along with all of its sub-commands. It is not modified,
+// as Resolve works on a fresh copy of the tree.
+func Resolve[T any](root *cobra.Command, args []string, env map[string]string) (*T, error) {
+	var src envSource
+	if env != nil {
+		src = mapEnv(env)
+	}
+	clone, envErrs, err := cloneTree(root, src, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	if len(envErrs) != 0 {
+		return nil, joinEnvErrors(envErrs)
+	}
+
+	// Cobra's ExecuteC always calls Find while setting up shell completion. As a side effect, it
+	// merges persistent flags into the flag sets that Traverse consults, so that e.g. boolean flags
+	// preceding a sub-command are recognized. Replicate this to find the same command.
+	_, _, _ = clone.Find(args)
+
+	var cmd *cobra.Command
+	var flags []string
+	if clone.TraverseChildren {
+		cmd, flags, err = clone.Traverse(args)
+	} else {
+		cmd, flags, err = clone.Find(args)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.ParseFlags(flags); err != nil {
+		return nil, cmd.FlagErrorFunc()(cmd, err)
+	}
+	if err := applyConfigFlags(cmd); err != nil {
+		return nil, err
+	}
+	if err := cmd.ValidateArgs(cmd.Flags().Args()); err != nil {
+		return nil, err
+	}
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		return nil, err
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return nil, err
+	}
+
+	b := lookupBinding(cmd)
+	if b == nil {
+		return nil, fmt.Errorf("command %q has no configuration", cmd.CommandPath())
+	}
+	cfg, ok := b.cfg.(*T)
+	if !ok {
+		return nil, fmt.Errorf("command %q has configuration type %T, not %T", cmd.CommandPath(), b.cfg, cfg)
+	}
+	result := *cfg
+	return &result, nil
+}
@@ -0,0 +1,56 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	type RootConf struct {
+		Verbose bool `flag:"persistent"`
+	}
+	type SubConf struct {
+		Name  string `flag:"required"`
+		Times int
+	}
+	hooksCalled := false
+	rootRun := func(cfg RootConf, cmd *cobra.Command, args []string) error {
+		hooksCalled = true
+		return nil
+	}
+	subRun := func(cfg SubConf, cmd *cobra.Command, args []string) error {
+		hooksCalled = true
+		return nil
+	}
+	root := Command("TEST", PersistentPreRun(rootRun), cobra.Command{Use: "root"}, RootConf{})
+	root.AddCommand(Command("TEST_SUB", Run(subRun), cobra.Command{Use: "sub"}, SubConf{Times: 1}))
+
+	cfg, err := Resolve[SubConf](root, []string{"--verbose", "sub", "--times", "3"},
+		map[string]string{"TEST_SUB_NAME": "env"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if *cfg != (SubConf{Name: "env", Times: 3}) {
+		t.Errorf("unexpected config: %+v", *cfg)
+	}
+	if hooksCalled {
+		t.Error("expected no hooks to be called")
+	}
+
+	if _, err := Resolve[SubConf](root, []string{"sub"}, map[string]string{}); err == nil ||
+		!strings.Contains(err.Error(), `required flag(s) "name" not set`) {
+		t.Errorf("expected required flag error, got %v", err)
+	}
+
+	var envErr *EnvError
+	if _, err := Resolve[SubConf](root, []string{"sub"}, map[string]string{"TEST_SUB_TIMES": "x"}); !errors.As(err, &envErr) {
+		t.Errorf("expected EnvError, got %v", err)
+	}
+
+	if _, err := Resolve[RootConf](root, []string{"sub", "--name", "foo"}, nil); err == nil ||
+		!strings.Contains(err.Error(), "has configuration type *nicecmd.SubConf") {
+		t.Errorf("expected type mismatch error, got %v", err)
+	}
+}
@@ -3,29 +3,55 @@ package nicecmd
 import (
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
-func slug(in string, sep rune) string {
+// Slug converts a Go identifier to lowercase words joined by sep, the way nicecmd derives flag
+// names from field names: Slug("PathToCSV", '-') is "path-to-csv".
+func Slug(in string, sep rune) string {
+	return convertCase(in, sep, false)
+}
+
+// ScreamingSnake converts a Go identifier to uppercase words joined by underscores, the way
+// nicecmd derives environment variable names from field names: ScreamingSnake("PathToCSV") is
+// "PATH_TO_CSV".
+func ScreamingSnake(in string) string {
+	return convertCase(in, '_', true)
+}
+
+// convertCase splits in into words before upper-case letters, and converts all letters to lower
+// or upper case. It works on the string directly, as it runs for every field of every command at
+// startup.
+func convertCase(in string, sep rune, upper bool) string {
 	var s strings.Builder
 	s.Grow(len(in) + len(in)/4)
 	start := false
-	runes := []rune(in)
-	for i := 0; i < len(runes); i++ {
-		r := runes[i]
+	for i := 0; i < len(in); {
+		r, size := utf8.DecodeRuneInString(in[i:])
 		if unicode.IsUpper(r) {
-			if start || (i > 0 && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			if start || (i > 0 && nextIsLower(in[i+size:])) {
 				s.WriteRune(sep)
 			}
-			s.WriteRune(unicode.ToLower(r))
+			r = unicode.ToLower(r)
+			if upper {
+				r = unicode.ToUpper(r)
+			}
+			s.WriteRune(r)
 			start = false
 		} else {
+			if upper {
+				r = unicode.ToUpper(r)
+			}
 			s.WriteRune(r)
 			start = true
 		}
+		i += size
 	}
 	return s.String()
 }
 
-func screamingSnake(in string) string {
-	return strings.ToUpper(slug(in, '_'))
+// nextIsLower reports whether the first rune of s is a lower-case letter.
+func nextIsLower(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return s != "" && unicode.IsLower(r)
 }
@@ -2,7 +2,7 @@ package nicecmd
 
 import "testing"
 
-func Test_slug(t *testing.T) {
+func TestSlug(t *testing.T) {
 	tests := []struct {
 		in   string
 		want string
@@ -20,17 +20,19 @@ func Test_slug(t *testing.T) {
 		{"firstNotLower", "first-not-lower"},
 		{"IP", "ip"},
 		{"IPMask", "ip-mask"},
+		{"ÄrgerÜberÖl", "ärger-über-öl"},
+		{"", ""},
 	}
 	for _, tt := range tests {
 		t.Run(tt.in, func(t *testing.T) {
-			if got := slug(tt.in, '-'); got != tt.want {
-				t.Errorf("slug(%q) = %q, want %q", tt.in, got, tt.want)
+			if got := Slug(tt.in, '-'); got != tt.want {
+				t.Errorf("Slug(%q) = %q, want %q", tt.in, got, tt.want)
 			}
 		})
 	}
 }
 
-func Test_screamingSnake(t *testing.T) {
+func TestScreamingSnake(t *testing.T) {
 	tests := []struct {
 		in   string
 		want string
@@ -38,12 +40,21 @@ func Test_screamingSnake(t *testing.T) {
 		{"CamelCase", "CAMEL_CASE"},
 		{"CamelCamelCase", "CAMEL_CAMEL_CASE"},
 		{"Camel2Camel2Case", "CAMEL2_CAMEL2_CASE"},
+		{"firstNotLower", "FIRST_NOT_LOWER"},
+		{"ÄrgerÜberÖl", "ÄRGER_ÜBER_ÖL"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.in, func(t *testing.T) {
-			if got := screamingSnake(tt.in); got != tt.want {
-				t.Errorf("screamingSnake(%q) = %q, want %q", tt.in, got, tt.want)
+			if got := ScreamingSnake(tt.in); got != tt.want {
+				t.Errorf("ScreamingSnake(%q) = %q, want %q", tt.in, got, tt.want)
 			}
 		})
 	}
 }
+
+func BenchmarkScreamingSnake(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ScreamingSnake("TLSCertificatePath")
+	}
+}
@@ -0,0 +1,26 @@
+package nicecmd
+
+// colors enables ANSI colors in usage strings.
+var colors = true
+
+// TestingT is the subset of testing.TB that TestMode needs.
+type TestingT interface {
+	Cleanup(func())
+	Fatalf(format string, args ...any)
+}
+
+// TestMode makes nicecmd behave reproducibly for the rest of test t: Instead of exiting the
+// program, Command fails the test, usage strings contain no ANSI colors, and Debug output is
+// disabled. Everything is restored when the test completes. As these settings are global, tests
+// that use TestMode must not run in parallel.
+func TestMode(t TestingT) {
+	prevExit, prevColors, prevDebug := osExitOrTestHook, colors, Debug
+	t.Cleanup(func() {
+		osExitOrTestHook, colors, Debug = prevExit, prevColors, prevDebug
+	})
+	osExitOrTestHook = func(code int) {
+		t.Fatalf("nicecmd: program exited with code %d", code)
+	}
+	colors = false
+	Debug = false
+}
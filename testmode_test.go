@@ -0,0 +1,51 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"strings"
+	"testing"
+)
+
+// fakeT records what TestMode does to a test.
+type fakeT struct {
+	cleanups []func()
+	fatal    string
+}
+
+func (t *fakeT) Cleanup(f func()) {
+	t.cleanups = append(t.cleanups, f)
+}
+
+func (t *fakeT) Fatalf(format string, args ...any) {
+	t.fatal = fmt.Sprintf(format, args...)
+}
+
+func TestTestMode(t *testing.T) {
+	t.Setenv("TEST_FOO", "foo")
+	ft := &fakeT{}
+	TestMode(ft)
+
+	cmd, err := TryCommand("TEST", Run(trivialRun), cobra.Command{Use: "test"}, TrivialConf{})
+	if err != nil {
+		t.Fatalf("command: %v", err)
+	}
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if strings.Contains(flag.Usage, "\033") {
+			t.Errorf("expected no colors in usage of %q, got %q", flag.Name, flag.Usage)
+		}
+	})
+
+	osExitOrTestHook(2)
+	if ft.fatal != "nicecmd: program exited with code 2" {
+		t.Errorf("expected exit to fail the test, got %q", ft.fatal)
+	}
+
+	for _, f := range ft.cleanups {
+		f()
+	}
+	if !colors {
+		t.Error("expected colors to be restored")
+	}
+}
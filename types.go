@@ -0,0 +1,155 @@
+package nicecmd
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TypeRegistry holds parse and format functions for types that nicecmd does not support natively,
+// e.g. types of third-party packages that cannot implement pflag.Value. Registered types take
+// precedence over built-in ones. A TypeRegistry is safe for concurrent use.
+type TypeRegistry struct {
+	mu         sync.RWMutex
+	types      map[reflect.Type]*typeReg
+	transforms map[string]func(s string) (string, error)
+}
+
+// typeReg is a type registration with its functions wrapped to work on any.
+type typeReg struct {
+	desc   string
+	parse  func(s string) (any, error)
+	format func(v any) string
+}
+
+// DefaultTypes is the registry used by commands that were not given one via WithTypeRegistry.
+var DefaultTypes = NewTypeRegistry()
+
+// NewTypeRegistry returns an empty registry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		types:      make(map[reflect.Type]*typeReg),
+		transforms: make(map[string]func(s string) (string, error)),
+	}
+}
+
+// RegisterType makes fields of type T bindable via reg, or via DefaultTypes if reg is nil. desc
+// names the type in help texts, e.g. "duration". format must produce a string that parse accepts.
+func RegisterType[T any](reg *TypeRegistry, desc string, parse func(s string) (T, error), format func(v T) string) {
+	if reg == nil {
+		reg = DefaultTypes
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.types[reflect.TypeFor[T]()] = &typeReg{
+		desc: desc,
+		parse: func(s string) (any, error) {
+			return parse(s)
+		},
+		format: func(v any) string {
+			return format(v.(T))
+		},
+	}
+}
+
+// RegisterEnvTransform makes the transformation available to the envTransform tag of fields bound
+// via reg, or via DefaultTypes if reg is nil. It receives the value of an environment variable and
+// returns the value to parse, e.g. to unwrap values that other systems encode. Registered
+// transformations take precedence over the built-in ones "base64", "trim" and "unquote".
+func RegisterEnvTransform(reg *TypeRegistry, name string, transform func(s string) (string, error)) {
+	if reg == nil {
+		reg = DefaultTypes
+	}
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.transforms[name] = transform
+}
+
+// builtinEnvTransforms are the transformations available without registration.
+var builtinEnvTransforms = map[string]func(s string) (string, error){
+	"base64": func(s string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(s)
+		return string(data), err
+	},
+	"trim": func(s string) (string, error) {
+		return strings.TrimSpace(s), nil
+	},
+	"unquote": func(s string) (string, error) {
+		if len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'' {
+			return s[1 : len(s)-1], nil
+		}
+		if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+			return strconv.Unquote(s)
+		}
+		return s, nil
+	},
+}
+
+// WithTypeRegistry binds the command's fields with types from reg instead of DefaultTypes.
+func WithTypeRegistry(reg *TypeRegistry) Option {
+	return func(o *options) {
+		o.types = reg
+	}
+}
+
+func (reg *TypeRegistry) lookup(t reflect.Type) *typeReg {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.types[t]
+}
+
+func (reg *TypeRegistry) lookupTransform(name string) func(s string) (string, error) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	if transform, ok := reg.transforms[name]; ok {
+		return transform
+	}
+	return builtinEnvTransforms[name]
+}
+
+// registeredValue implements pflag.Value for a field of a registered type.
+type registeredValue struct {
+	ptr reflect.Value
+	reg *typeReg
+}
+
+func (v *registeredValue) Set(s string) error {
+	parsed, err := v.reg.parse(s)
+	if err != nil {
+		return err
+	}
+	v.ptr.Elem().Set(reflect.ValueOf(parsed))
+	return nil
+}
+
+func (v *registeredValue) String() string {
+	return v.reg.format(v.ptr.Elem().Interface())
+}
+
+func (v *registeredValue) Type() string {
+	return v.reg.desc
+}
+
+// checkRoundTrip verifies that the non-zero value of a field of a registered type can be parsed
+// back from its string representation.
+func (v *registeredValue) checkRoundTrip(opts fieldOpts) error {
+	value := v.ptr.Elem()
+	if value.IsZero() {
+		return nil
+	}
+	text := v.String()
+	parsed, err := v.reg.parse(text)
+	if err != nil {
+		if opts.secret {
+			return fmt.Errorf("cannot parse its own string representation %s", redacted)
+		}
+		return fmt.Errorf("cannot parse its own string representation %q: %w", text, err)
+	}
+	if !reflect.DeepEqual(parsed, value.Interface()) {
+		return fmt.Errorf("string representation %s does not parse back to the same value", traceValue(opts, text))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package nicecmd
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type point struct {
+	X, Y int
+}
+
+func parsePoint(s string) (p point, err error) {
+	_, err = fmt.Sscanf(s, "%d:%d", &p.X, &p.Y)
+	return
+}
+
+func formatPoint(p point) string {
+	return fmt.Sprintf("%d:%d", p.X, p.Y)
+}
+
+func TestTypeRegistry(t *testing.T) {
+	reg := NewTypeRegistry()
+	RegisterType(reg, "point", parsePoint, formatPoint)
+
+	type Conf struct {
+		Origin point
+	}
+	t.Setenv("TEST_ORIGIN", "1:2")
+	cmd := &cobra.Command{}
+	cfg := Conf{Origin: point{3, 4}}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithTypeRegistry(reg)); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Origin != (point{1, 2}) {
+		t.Errorf("expected env to be applied, got %+v", cfg.Origin)
+	}
+	flag := cmd.Flags().Lookup("origin")
+	if flag == nil || flag.Value.Type() != "point" || flag.DefValue != "3:4" {
+		t.Fatalf("unexpected flag: %+v", flag)
+	}
+
+	// Without the registry, the struct is flattened as usual
+	cmd = &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &Conf{}); err != nil {
+		t.Fatalf("bind without registry: %v", err)
+	}
+	if cmd.Flags().Lookup("origin-x") == nil {
+		t.Error("expected nested struct flags without registry")
+	}
+}
+
+func TestTypeRegistry_OverridesBuiltin(t *testing.T) {
+	reg := NewTypeRegistry()
+	RegisterType(reg, "seconds", func(s string) (time.Duration, error) {
+		var n int
+		_, err := fmt.Sscanf(s, "%d", &n)
+		return time.Duration(n) * time.Second, err
+	}, func(d time.Duration) string {
+		return fmt.Sprint(int(d.Seconds()))
+	})
+	var cfg struct {
+		Timeout time.Duration
+	}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithTypeRegistry(reg)); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := cmd.Flags().Set("timeout", "90"); err != nil || cfg.Timeout != 90*time.Second {
+		t.Errorf("expected registered parser to be used, got %v, %v", cfg.Timeout, err)
+	}
+}
+
+func TestTypeRegistry_RoundTrip(t *testing.T) {
+	reg := NewTypeRegistry()
+	RegisterType(reg, "point", parsePoint, func(p point) string {
+		return fmt.Sprintf("(%d, %d)", p.X, p.Y)
+	})
+	cfg := struct {
+		Origin point
+	}{Origin: point{1, 2}}
+	var bindErr *BindError
+	err := TryBindConfig("TEST", &cobra.Command{}, &cfg, WithTypeRegistry(reg))
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected BindError for broken round trip, got %v", err)
+	}
+}
+
+func TestTypeRegistry_Concurrent(t *testing.T) {
+	reg := NewTypeRegistry()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			RegisterType(reg, "point", parsePoint, formatPoint)
+		}()
+		go func() {
+			defer wg.Done()
+			reg.lookup(reflect.TypeFor[point]())
+		}()
+	}
+	wg.Wait()
+	if reg.lookup(reflect.TypeFor[point]()) == nil {
+		t.Error("expected point to be registered")
+	}
+}
+
+func TestRegisterEnvTransform(t *testing.T) {
+	reg := NewTypeRegistry()
+	RegisterEnvTransform(reg, "reverse", func(s string) (string, error) {
+		r := []rune(s)
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r), nil
+	})
+	RegisterEnvTransform(reg, "trim", func(s string) (string, error) {
+		return s + "!", nil
+	})
+
+	type Conf struct {
+		Token     string   `envTransform:"base64"`
+		Name      string   `envTransform:"unquote,reverse"`
+		Padded    string   `envTransform:"trim"`
+		Hosts     []string `envTransform:"base64,trim"`
+		Untouched string
+	}
+	var cfg Conf
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithTypeRegistry(reg), WithEnviron([]string{
+		"TEST_TOKEN=aHVudGVyMg==",
+		`TEST_NAME="olleh"`,
+		"TEST_PADDED= x ",
+		"TEST_HOSTS_0=IGE=",
+		"TEST_HOSTS_1=Yg==",
+		"TEST_UNTOUCHED=aGk=",
+	}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	want := Conf{Token: "hunter2", Name: "hello", Padded: " x !", Hosts: []string{" a!", "b!"}, Untouched: "aGk="}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("expected transformed values %+v, got %+v", want, cfg)
+	}
+	if err := cmd.ParseFlags([]string{"--token=plain"}); err != nil || cfg.Token != "plain" {
+		t.Errorf("expected flags not to be transformed, got %q (%v)", cfg.Token, err)
+	}
+
+	var envErr *EnvError
+	err = TryBindConfig("TEST", &cobra.Command{}, &Conf{}, WithTypeRegistry(reg), WithEnviron([]string{"TEST_TOKEN=%%%"}))
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_TOKEN" {
+		t.Errorf("expected *EnvError for value that fails to transform, got %v", err)
+	}
+	var bindErr *BindError
+	err = TryBindConfig("TEST", &cobra.Command{}, &Conf{}, WithEnviron(nil))
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected *BindError for transformation missing from the registry, got %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"slices"
+	"strings"
+)
+
+const (
+	// annotationUsage holds a flag's usage without the suffixes added by decorateUsage.
+	annotationUsage = "nicecmd_usage"
+
+	// annotationChoices holds the values of the flag's choices tag.
+	annotationChoices = "nicecmd_choices"
+
+	// annotationTogether holds the flags that must be given along with the flag, see groupTogether.
+	annotationTogether = "nicecmd_together"
+
+	// annotationEnvState records whether the flag's environment variable was applied, see envUnset.
+	// Flags without it did not have their environment variable processed.
+	annotationEnvState = "nicecmd_env_state"
+
+	// annotationEnvName holds the environment variable that was applied, one of annotationEnv.
+	annotationEnvName = "nicecmd_env_name"
+
+	// annotationEnvValue holds the value of the flag's environment variable, unless it is secret.
+	annotationEnvValue = "nicecmd_env_value"
+
+	// annotationHelpGroup holds the title of the section that lists the flag in help texts.
+	annotationHelpGroup = "nicecmd_help_group"
+)
+
+const (
+	envUnset   = "unset"
+	envApplied = "applied"
+	envInvalid = "invalid"
+)
+
+func setAnnotation(flag *pflag.Flag, key string, values ...string) {
+	if flag.Annotations == nil {
+		flag.Annotations = make(map[string][]string, 4) // enough for nicecmd's own annotations
+	}
+	flag.Annotations[key] = values
+}
+
+// decorateUsage derives the usage of flag from its original usage and its annotations, appending
+// e.g. "(one of a, b)", "(required)", "(with --bar)" and "(env FOO)". The result only depends on
+// the annotations, so this can be called again whenever they change.
+func decorateUsage(flag *pflag.Flag) {
+	base, ok := flag.Annotations[annotationUsage]
+	if !ok {
+		base = []string{flag.Usage}
+		setAnnotation(flag, annotationUsage, flag.Usage)
+	}
+	var usage strings.Builder
+	usage.WriteString(base[0])
+	suffix := func(s string) {
+		if usage.Len() != 0 {
+			usage.WriteByte(' ')
+		}
+		usage.WriteString("(" + s + ")")
+	}
+	if choices := flag.Annotations[annotationChoices]; len(choices) != 0 {
+		suffix(fmt.Sprintf(DefaultMessages.Choices, strings.Join(choices, ", ")))
+	}
+	if isRequired(flag) {
+		suffix(DefaultMessages.Required)
+	}
+	if others := flag.Annotations[annotationTogether]; len(others) != 0 {
+		suffix(fmt.Sprintf(DefaultMessages.Together, strings.Join(others, ", ")))
+	}
+	if state := flag.Annotations[annotationEnvState]; len(state) != 0 {
+		env := strings.Join(flag.Annotations[annotationEnv], ", ")
+		if name := flag.Annotations[annotationEnvName]; len(name) != 0 {
+			env = name[0]
+		}
+		switch {
+		case state[0] == envUnset:
+			suffix(fmt.Sprintf(DefaultMessages.Env, env))
+		case isSecret(flag):
+			suffix(colorize(envColor(state[0]), fmt.Sprintf(DefaultMessages.EnvSetSecret, env)))
+		default:
+			value := flag.Annotations[annotationEnvValue][0]
+			suffix(colorize(envColor(state[0]), fmt.Sprintf(DefaultMessages.EnvSet, env, value)))
+		}
+	}
+	flag.Usage = usage.String()
+}
+
+// envColor returns the ANSI color for an environment variable in the given state.
+func envColor(state string) string {
+	if state == envInvalid {
+		return "31" // red
+	}
+	return "32" // green
+}
+
+func init() {
+	cobra.AddTemplateFunc("nicecmdFlagUsages", groupedFlagUsages)
+}
+
+// groupUsageTemplate makes the usage template of cmd list flags in the sections of their helpgroup
+// tags. Custom templates are left alone unless they use FlagUsages like Cobra's default template,
+// but can call nicecmdFlagUsages themselves, e.g. {{nicecmdFlagUsages .LocalFlags}}.
+func groupUsageTemplate(cmd *cobra.Command) {
+	tmpl := cmd.UsageTemplate()
+	tmpl = strings.ReplaceAll(tmpl, ".LocalFlags.FlagUsages", "nicecmdFlagUsages .LocalFlags")
+	tmpl = strings.ReplaceAll(tmpl, ".InheritedFlags.FlagUsages", "nicecmdFlagUsages .InheritedFlags")
+	cmd.SetUsageTemplate(tmpl)
+}
+
+// groupedFlagUsages is like fs.FlagUsages, but lists flags with a helpgroup tag in a section per
+// group after the other flags. Sections are ordered by title, and each aligns its own columns.
+func groupedFlagUsages(fs *pflag.FlagSet) string {
+	sets := make(map[string]*pflag.FlagSet)
+	fs.VisitAll(func(flag *pflag.Flag) {
+		var title string
+		if group := flag.Annotations[annotationHelpGroup]; len(group) != 0 {
+			title = group[0]
+		}
+		if sets[title] == nil {
+			sets[title] = pflag.NewFlagSet(title, pflag.ContinueOnError)
+			sets[title].SortFlags = fs.SortFlags
+		}
+		sets[title].AddFlag(flag)
+	})
+	titles := make([]string, 0, len(sets))
+	for title := range sets {
+		titles = append(titles, title)
+	}
+	slices.Sort(titles) // flags without a group come first
+	var out strings.Builder
+	for _, title := range titles {
+		usages := sets[title].FlagUsages()
+		if usages == "" {
+			continue // all of the group's flags are hidden
+		}
+		if title != "" {
+			if out.Len() != 0 {
+				out.WriteByte('\n')
+			}
+			out.WriteString(title + ":\n")
+		}
+		out.WriteString(usages)
+	}
+	return out.String()
+}
@@ -0,0 +1,88 @@
+package nicecmd
+
+import (
+	"github.com/spf13/cobra"
+	"strings"
+	"testing"
+)
+
+func TestDecorateUsage(t *testing.T) {
+	type Conf struct {
+		Name  string `flag:"required" usage:"your name"`
+		Token string `flag:"secret"`
+		Port  int    `usage:"port to listen on"`
+		Plain bool   `env:"-"`
+	}
+	t.Setenv("TEST_TOKEN", "t0ps3cret")
+	t.Setenv("TEST_PORT", "x")
+	ft := &fakeT{}
+	TestMode(ft)
+	defer func() {
+		for _, f := range ft.cleanups {
+			f()
+		}
+	}()
+	cmd := &cobra.Command{}
+	_ = TryBindConfig("TEST", cmd, &Conf{})
+
+	want := map[string]string{
+		"name":  "your name (required) (env TEST_NAME)",
+		"token": "(env TEST_TOKEN=<redacted>)",
+		"port":  `port to listen on (env TEST_PORT="x")`,
+		"plain": "",
+	}
+	check := func() {
+		t.Helper()
+		for name, usage := range want {
+			if got := cmd.Flags().Lookup(name).Usage; got != usage {
+				t.Errorf("unexpected usage of %q, want %q, got %q", name, usage, got)
+			}
+		}
+	}
+	check()
+
+	// Decorating again must not add suffixes twice
+	for name := range want {
+		decorateUsage(cmd.Flags().Lookup(name))
+	}
+	check()
+}
+
+func TestHelpGroup(t *testing.T) {
+	type NetConf struct {
+		Listen  string
+		Timeout int `helpgroup:"Tuning"`
+	}
+	type Conf struct {
+		Name  string
+		Net   NetConf `helpgroup:"Networking"`
+		Debug bool    `helpgroup:"Debugging" flag:"negatable"`
+		Trace bool    `helpgroup:"Tracing" flag:"hidden"`
+	}
+	cmd := &cobra.Command{Use: "test", Run: func(*cobra.Command, []string) {}}
+	if err := TryBindConfig("TEST", cmd, &Conf{}, WithEnvironment(false)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd.InitDefaultHelpFlag()
+	want := `Flags:
+  -h, --help          help for test
+      --name string
+
+Debugging:
+      --debug
+      --no-debug   sets --debug to false
+
+Networking:
+      --net-listen string
+
+Tuning:
+      --net-timeout int
+`
+	lines := strings.Split(cmd.UsageString(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	if usage := strings.Join(lines, "\n"); !strings.Contains(usage, want) {
+		t.Errorf("expected grouped flags in usage:\n%s\ngot:\n%s", want, usage)
+	}
+}
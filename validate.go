@@ -0,0 +1,231 @@
+package nicecmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// checkedValue checks the values of a flag after they are set, and restores the previous value of
+// its field if a check fails. This way, constraint tags hold for values of flags, environment
+// variables and configuration files alike.
+type checkedValue struct {
+	pflag.Value
+	field reflect.Value
+	check func(v pflag.Value) error
+}
+
+func (v *checkedValue) Set(s string) error {
+	prev := reflect.New(v.field.Type()).Elem()
+	prev.Set(v.field)
+	if err := v.Value.Set(s); err != nil {
+		return err
+	}
+	if err := v.check(v.Value); err != nil {
+		v.field.Set(prev)
+		return err
+	}
+	return nil
+}
+
+// constrain wraps the value of param to enforce the constraint tags of its field, and verifies
+// that a non-zero default satisfies them.
+func (b *binder) constrain(param *pflag.Flag, field reflect.Value, tags fieldTags) error {
+	var checks []func(v pflag.Value) error
+	if tags.choices != nil {
+		checks = append(checks, checkChoices(tags.choices))
+	}
+	if tags.min != "" || tags.max != "" {
+		check, err := b.checkRange(field, tags)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
+	}
+	if tags.pattern != "" {
+		if field.Kind() != reflect.String {
+			return fmt.Errorf("pattern requires a string, got %s", field.Type())
+		}
+		re, err := regexp.Compile(tags.pattern)
+		if err != nil {
+			return fmt.Errorf("pattern: %w", err)
+		}
+		checks = append(checks, func(pflag.Value) error {
+			if !re.MatchString(field.String()) {
+				return fmt.Errorf(DefaultMessages.NoMatch, tags.pattern)
+			}
+			return nil
+		})
+	}
+	if tags.schemes != nil {
+		check, err := checkSchemes(param, tags.schemes)
+		if err != nil {
+			return err
+		}
+		checks = append(checks, check)
+	}
+	exists := tags.hasOption(optExists)
+	if exists && field.Kind() != reflect.String {
+		return fmt.Errorf("exists requires a string, got %s", field.Type())
+	}
+	if len(checks) == 0 && !exists {
+		return nil
+	}
+	if _, ok := param.Value.(pflag.SliceValue); ok || isMapFlag(param) {
+		return fmt.Errorf("constraints require a single value, got %s", param.Value.Type())
+	}
+	check := func(v pflag.Value) error {
+		for _, c := range checks {
+			if err := c(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !field.IsZero() {
+		if err := check(param.Value); err != nil {
+			return fmt.Errorf("default value: %w", err)
+		}
+	}
+	if exists {
+		// Not checked for defaults, which commonly name files that only exist where deployed
+		dir := strings.HasPrefix(tags.complete, completeDir)
+		checks = append(checks, func(pflag.Value) error {
+			return checkExists(b.files, field.String(), dir)
+		})
+	}
+	param.Value = &checkedValue{Value: param.Value, field: field, check: check}
+
+	if tags.choices != nil {
+		setAnnotation(param, annotationChoices, tags.choices...)
+	}
+	return nil
+}
+
+const (
+	completeFile = "file"
+	completeDir  = "dir"
+)
+
+// registerCompletion sets up shell completion of param according to the tags of its field.
+func (b *binder) registerCompletion(fs *pflag.FlagSet, param *pflag.Flag, tags fieldTags) error {
+	if tags.choices != nil && tags.complete != "" {
+		return errors.New("choices and complete are mutually exclusive")
+	}
+	if tags.choices != nil {
+		complete := cobra.FixedCompletions(tags.choices, cobra.ShellCompDirectiveNoFileComp)
+		return b.cmd.RegisterFlagCompletionFunc(param.Name, complete)
+	}
+	if tags.complete == "" {
+		if tags.hasOption(optExists) {
+			return errors.New(`exists requires complete:"file" or complete:"dir"`)
+		}
+		return nil
+	}
+	kind, exts, _ := strings.Cut(tags.complete, ",")
+	switch {
+	case kind == completeFile && exts == "":
+		return cobra.MarkFlagFilename(fs, param.Name)
+	case kind == completeFile:
+		return cobra.MarkFlagFilename(fs, param.Name, strings.Split(exts, ",")...)
+	case kind == completeDir && exts == "":
+		return cobra.MarkFlagDirname(fs, param.Name)
+	}
+	return fmt.Errorf(`expected complete:"file", complete:"file,<ext>,..." or complete:"dir", got %q`, tags.complete)
+}
+
+// checkExists verifies that path is a readable file, or an existing directory if dir is set.
+// Empty paths count as unset.
+func checkExists(files fileSystem, path string, dir bool) error {
+	if path == "" {
+		return nil
+	}
+	info, err := files.stat(path)
+	switch {
+	case err != nil:
+		return err
+	case dir && !info.IsDir():
+		return fmt.Errorf(DefaultMessages.NotDir, path)
+	case !dir && info.IsDir():
+		return fmt.Errorf(DefaultMessages.IsDir, path)
+	case !dir:
+		f, err := files.open(path)
+		if err != nil {
+			return err
+		}
+		return f.Close()
+	}
+	return nil
+}
+
+// checkChoices returns a check that the formatted value is one of choices.
+func checkChoices(choices []string) func(v pflag.Value) error {
+	return func(v pflag.Value) error {
+		if !slices.Contains(choices, v.String()) {
+			return fmt.Errorf(DefaultMessages.InvalidChoice, strings.Join(choices, ", "))
+		}
+		return nil
+	}
+}
+
+// checkSchemes returns a check that the URL of param has one of the given schemes. Empty URLs count
+// as unset.
+func checkSchemes(param *pflag.Flag, schemes []string) (func(v pflag.Value) error, error) {
+	value, ok := param.Value.(*urlValue)
+	if !ok {
+		return nil, fmt.Errorf("schemes requires a URL, got %s", param.Value.Type())
+	}
+	return func(pflag.Value) error {
+		if u := value.get(); u != nil && *u != (url.URL{}) && !slices.Contains(schemes, u.Scheme) {
+			return fmt.Errorf(DefaultMessages.InvalidScheme, strings.Join(schemes, ", "))
+		}
+		return nil
+	}, nil
+}
+
+// checkRange returns a check that the numeric field is within the bounds of its min and max tags.
+// The bounds are parsed like the flag, e.g. "1s" for a time.Duration.
+func (b *binder) checkRange(field reflect.Value, tags fieldTags) (func(v pflag.Value) error, error) {
+	var compare func(a, b reflect.Value) int
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.Int(), b.Int()) }
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.Uint(), b.Uint()) }
+	case reflect.Float32, reflect.Float64:
+		compare = func(a, b reflect.Value) int { return cmp.Compare(a.Float(), b.Float()) }
+	default:
+		return nil, fmt.Errorf("min and max require a number or duration, got %s", field.Type())
+	}
+	var lower, upper reflect.Value
+	var err error
+	if tags.min != "" {
+		if lower, err = b.parseTagDefault(field, tags.encoding, tags.min); err != nil {
+			return nil, fmt.Errorf("min %q: %w", tags.min, err)
+		}
+	}
+	if tags.max != "" {
+		if upper, err = b.parseTagDefault(field, tags.encoding, tags.max); err != nil {
+			return nil, fmt.Errorf("max %q: %w", tags.max, err)
+		}
+	}
+	if lower.IsValid() && upper.IsValid() && compare(lower, upper) > 0 {
+		return nil, fmt.Errorf("min %q is greater than max %q", tags.min, tags.max)
+	}
+	return func(pflag.Value) error {
+		if lower.IsValid() && compare(field, lower) < 0 {
+			return fmt.Errorf(DefaultMessages.TooSmall, tags.min)
+		}
+		if upper.IsValid() && compare(field, upper) > 0 {
+			return fmt.Errorf(DefaultMessages.TooLarge, tags.max)
+		}
+		return nil
+	}, nil
+}
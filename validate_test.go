@@ -0,0 +1,248 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConstrain_Choices(t *testing.T) {
+	type ChoicesConfig struct {
+		Format string `choices:"json,yaml,table" usage:"output format"`
+		Level  int    `choices:"1,2,3"`
+	}
+	cfg := ChoicesConfig{Format: "table"}
+	cmd := &cobra.Command{Use: "test"}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_LEVEL=2"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Level != 2 {
+		t.Errorf("expected valid choice from environment, got %d", cfg.Level)
+	}
+	if err := cmd.ParseFlags([]string{"--format", "xml"}); err == nil || !strings.Contains(err.Error(), "must be one of json, yaml, table") {
+		t.Errorf("expected error for invalid choice, got %v", err)
+	}
+	if cfg.Format != "table" {
+		t.Errorf("expected invalid choice not to be applied, got %q", cfg.Format)
+	}
+	if usage := cmd.Flags().Lookup("format").Usage; usage != "output format (one of json, yaml, table) (env TEST_FORMAT)" {
+		t.Errorf("expected choices in usage, got %q", usage)
+	}
+	completions, _ := cmd.GetFlagCompletionFunc("format")
+	if completions == nil {
+		t.Fatal("expected completion of choices")
+	}
+	if values, _ := completions(cmd, nil, ""); strings.Join(values, ",") != "json,yaml,table" {
+		t.Errorf("expected choices as completions, got %v", values)
+	}
+
+	var envErr *EnvError
+	err := TryBindConfig("TEST", &cobra.Command{}, &ChoicesConfig{}, WithEnviron([]string{"TEST_FORMAT=xml"}))
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_FORMAT" {
+		t.Errorf("expected *EnvError for invalid choice, got %v", err)
+	}
+	var bindErr *BindError
+	err = TryBindConfig("TEST", &cobra.Command{}, &ChoicesConfig{Format: "xml"}, WithEnviron(nil))
+	if !errors.As(err, &bindErr) || !strings.Contains(err.Error(), "default value: must be one of") {
+		t.Errorf("expected *BindError for invalid default, got %v", err)
+	}
+	err = TryBindConfig("TEST", &cobra.Command{}, &struct {
+		Formats []string `choices:"a,b"`
+	}{}, WithEnviron(nil))
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected *BindError for choices of a slice, got %v", err)
+	}
+}
+
+func TestConstrain_Range(t *testing.T) {
+	type RangeConfig struct {
+		Port    int           `min:"1" max:"65535"`
+		Ratio   float64       `max:"1"`
+		Timeout time.Duration `min:"1s"`
+	}
+	cfg := RangeConfig{Port: 80}
+	cmd := &cobra.Command{Use: "test"}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_TIMEOUT=5s"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	for _, test := range []struct {
+		args []string
+		msg  string
+	}{
+		{args: []string{"--port", "0"}, msg: `invalid argument "0" for "--port" flag: must be at least 1`},
+		{args: []string{"--port", "65536"}, msg: `invalid argument "65536" for "--port" flag: must be at most 65535`},
+		{args: []string{"--ratio", "1.5"}, msg: `invalid argument "1.5" for "--ratio" flag: must be at most 1`},
+		{args: []string{"--timeout", "10ms"}, msg: `invalid argument "10ms" for "--timeout" flag: must be at least 1s`},
+	} {
+		if err := cmd.ParseFlags(test.args); err == nil || err.Error() != test.msg {
+			t.Errorf("expected error %q for %v, got %v", test.msg, test.args, err)
+		}
+	}
+	if cfg != (RangeConfig{Port: 80, Timeout: 5 * time.Second}) {
+		t.Errorf("expected values out of range not to be applied, got %+v", cfg)
+	}
+
+	var envErr *EnvError
+	err := TryBindConfig("TEST", &cobra.Command{}, &RangeConfig{}, WithEnviron([]string{"TEST_PORT=70000"}))
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_PORT" {
+		t.Errorf("expected *EnvError naming the variable, got %v", err)
+	}
+	var bindErr *BindError
+	for _, conf := range []any{
+		&RangeConfig{Port: 70000},
+		&struct {
+			Name string `min:"1"`
+		}{},
+		&struct {
+			Port int `min:"10" max:"1"`
+		}{},
+		&struct {
+			Port int `min:"x"`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("expected *BindError for %+v, got %v", conf, err)
+		}
+	}
+}
+
+func TestConstrain_Pattern(t *testing.T) {
+	type PatternConfig struct {
+		Name  string `pattern:"^[a-z0-9-]+$"`
+		Token string `pattern:"^[A-Z]+$" flag:"secret"`
+	}
+	var cfg PatternConfig
+	cmd := &cobra.Command{Use: "test"}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_NAME=web-1"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--name", "Web_1"}); err == nil || !strings.Contains(err.Error(), "must match ^[a-z0-9-]+$") {
+		t.Errorf("expected error for value not matching the pattern, got %v", err)
+	}
+	if cfg.Name != "web-1" {
+		t.Errorf("expected value not matching the pattern not to be applied, got %q", cfg.Name)
+	}
+
+	var envErr *EnvError
+	err := TryBindConfig("TEST", &cobra.Command{}, &PatternConfig{}, WithEnviron([]string{"TEST_TOKEN=abc"}))
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_TOKEN" || strings.Contains(err.Error(), "abc") {
+		t.Errorf("expected redacted *EnvError naming the variable, got %v", err)
+	}
+	var bindErr *BindError
+	for _, conf := range []any{
+		&PatternConfig{Name: "Web"},
+		&struct {
+			Port int `pattern:"^1"`
+		}{},
+		&struct {
+			Name string `pattern:"("`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("expected *BindError for %+v, got %v", conf, err)
+		}
+	}
+}
+
+func TestConstrain_Schemes(t *testing.T) {
+	type EndpointConfig struct {
+		API    *url.URL `schemes:"https,wss"`
+		Mirror url.URL  `schemes:"https"`
+	}
+	var cfg EndpointConfig
+	cmd := &cobra.Command{Use: "test"}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_API=wss://example.com/ws"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.API == nil || cfg.API.Host != "example.com" {
+		t.Errorf("expected URL from the environment, got %v", cfg.API)
+	}
+	if err := cmd.ParseFlags([]string{"--mirror", "http://example.com"}); err == nil || !strings.Contains(err.Error(), "scheme must be one of https") {
+		t.Errorf("expected error for URL with another scheme, got %v", err)
+	}
+	if cfg.Mirror != (url.URL{}) {
+		t.Errorf("expected URL with another scheme not to be applied, got %v", cfg.Mirror)
+	}
+
+	var bindErr *BindError
+	for _, conf := range []any{
+		&EndpointConfig{API: &url.URL{Scheme: "http", Host: "example.com"}},
+		&struct {
+			Host string `schemes:"https"`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("expected *BindError for %+v, got %v", conf, err)
+		}
+	}
+}
+
+func TestCompletion_Paths(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	type PathConfig struct {
+		Config  string   `complete:"file,yaml,yml" flag:"exists"`
+		Data    string   `complete:"dir" flag:"exists"`
+		Include []string `complete:"file"`
+		Log     string   `complete:"file" flag:"exists"`
+	}
+	cfg := PathConfig{Log: filepath.Join(dir, "missing.log")}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_DATA=" + dir})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if exts := cmd.Flags().Lookup("config").Annotations[cobra.BashCompFilenameExt]; strings.Join(exts, ",") != "yaml,yml" {
+		t.Errorf("expected completion of extensions, got %v", exts)
+	}
+	if dirs := cmd.Flags().Lookup("data").Annotations[cobra.BashCompSubdirsInDir]; dirs == nil {
+		t.Error("expected completion of directories")
+	}
+	if _, ok := cmd.Flags().Lookup("include").Annotations[cobra.BashCompFilenameExt]; !ok {
+		t.Error("expected completion of files for slices")
+	}
+	if err := cmd.ParseFlags([]string{"--config", file}); err != nil || cfg.Config != file {
+		t.Errorf("expected existing file to be accepted, got %q (%v)", cfg.Config, err)
+	}
+	for _, args := range [][]string{
+		{"--config", filepath.Join(dir, "missing.yaml")},
+		{"--config", dir},
+		{"--data", file},
+	} {
+		if err := cmd.ParseFlags(args); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+
+	var envErr *EnvError
+	err := TryBindConfig("TEST", &cobra.Command{}, &PathConfig{}, WithEnviron([]string{"TEST_CONFIG=" + dir}))
+	if !errors.As(err, &envErr) || envErr.Name != "TEST_CONFIG" {
+		t.Errorf("expected *EnvError for a directory, got %v", err)
+	}
+	var bindErr *BindError
+	for name, conf := range map[string]any{
+		"unknown": &struct {
+			Path string `complete:"socket"`
+		}{},
+		"dir with extensions": &struct {
+			Path string `complete:"dir,d"`
+		}{},
+		"with choices": &struct {
+			Path string `complete:"file" choices:"a,b"`
+		}{},
+		"exists without complete": &struct {
+			Path string `flag:"exists"`
+		}{},
+	} {
+		if err := TryBindConfig("TEST", &cobra.Command{}, conf, WithEnviron(nil)); !errors.As(err, &bindErr) {
+			t.Errorf("%s: expected *BindError, got %v", name, err)
+		}
+	}
+}
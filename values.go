@@ -0,0 +1,280 @@
+package nicecmd
+
+import (
+	"encoding"
+	"fmt"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"io"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ParseValue parses s into a new value of type t, exactly like a flag or environment variable of
+// that type would be parsed. Use it to give config-file loaders or fuzz tests the same semantics as
+// BindConfig. Types that require an encoding tag, such as []byte, are not supported.
+func ParseValue(t reflect.Type, s string) (any, error) {
+	flag, value, err := valueFlag(reflect.Zero(t), "", DefaultTypes)
+	if err != nil {
+		return nil, err
+	}
+	if err := flag.Value.Set(s); err != nil {
+		return nil, err
+	}
+	return value.Interface(), nil
+}
+
+// FormatValue formats v like the default value of a flag of v's type is shown in usage strings.
+// Note that pflag wraps slices in brackets there, which ParseValue does not accept.
+func FormatValue(v any) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("cannot format nil")
+	}
+	flag, _, err := valueFlag(reflect.ValueOf(v), "", DefaultTypes)
+	if err != nil {
+		return "", err
+	}
+	return flag.Value.String(), nil
+}
+
+// valueFlag binds a copy of value to a throwaway flag, and returns the flag and the copy.
+func valueFlag(value reflect.Value, encoding string, types *TypeRegistry) (*pflag.Flag, reflect.Value, error) {
+	tag := `env:"-"`
+	if encoding != "" {
+		tag += fmt.Sprintf(` encoding:%q`, encoding)
+	}
+	holder := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Value",
+		Type: value.Type(),
+		Tag:  reflect.StructTag(tag),
+	}})).Elem()
+	holder.Field(0).Set(value)
+
+	cmd := &cobra.Command{}
+	cmd.SetOut(io.Discard)
+	cmd.SetErr(io.Discard)
+	b := &binder{cmd: cmd, env: mapEnv(nil), types: types, keepValues: true}
+	if err := b.bindStruct("", "", "", fieldOpts{}, holder); err != nil {
+		return nil, reflect.Value{}, fmt.Errorf("type %s: %w", value.Type(), err)
+	}
+	flag := cmd.Flags().Lookup("value")
+	if flag == nil {
+		return nil, reflect.Value{}, fmt.Errorf("type %s is not supported", value.Type())
+	}
+	return flag, holder.Field(0), nil
+}
+
+// parseTagDefault parses s like the flag of a field with the given encoding, for defaults given
+// via struct tags.
+func (b *binder) parseTagDefault(value reflect.Value, encoding, s string) (reflect.Value, error) {
+	flag, parsed, err := valueFlag(reflect.Zero(value.Type()), encoding, b.types)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if err := flag.Value.Set(s); err != nil {
+		return reflect.Value{}, err
+	}
+	return parsed, nil
+}
+
+// aliasValue is the value of a flag added via the aliases tag. Setting it marks the actual flag as
+// changed, so that required flags and flag groups are satisfied by aliases as well.
+type aliasValue struct {
+	pflag.Value
+	flag *pflag.Flag
+}
+
+func (v *aliasValue) Set(s string) error {
+	if err := v.Value.Set(s); err != nil {
+		return err
+	}
+	v.flag.Changed = true
+	return nil
+}
+
+// locationValue is the value of a *time.Location flag, e.g. "Europe/Berlin" or "UTC", loaded via
+// time.LoadLocation. Programs for systems without time zone database should import time/tzdata.
+// An empty value stands for nil.
+type locationValue struct {
+	ptr **time.Location
+}
+
+func (v *locationValue) Set(s string) error {
+	if s == "" {
+		*v.ptr = nil
+		return nil
+	}
+	loc, err := time.LoadLocation(s)
+	if err != nil {
+		return err
+	}
+	*v.ptr = loc
+	return nil
+}
+
+func (v *locationValue) String() string {
+	if *v.ptr == nil {
+		return ""
+	}
+	return (*v.ptr).String()
+}
+
+func (v *locationValue) Type() string {
+	return "timezone"
+}
+
+// urlValue is the value of a url.URL or *url.URL flag. Values must be absolute URLs, and are shown
+// normalized as by url.URL.String. An empty value stands for the zero URL or nil.
+type urlValue struct {
+	value *url.URL  // for url.URL fields
+	ptr   **url.URL // for *url.URL fields
+}
+
+func (v *urlValue) Set(s string) error {
+	var u *url.URL
+	if s != "" {
+		var err error
+		if u, err = url.Parse(s); err != nil {
+			return err
+		}
+		if u.Scheme == "" {
+			return fmt.Errorf("invalid URL %q: missing scheme, expected e.g. https://example.com", s)
+		}
+	}
+	switch {
+	case v.ptr != nil:
+		*v.ptr = u
+	case u != nil:
+		*v.value = *u
+	default:
+		*v.value = url.URL{}
+	}
+	return nil
+}
+
+func (v *urlValue) get() *url.URL {
+	if v.ptr != nil {
+		return *v.ptr
+	}
+	return v.value
+}
+
+func (v *urlValue) String() string {
+	if u := v.get(); u != nil {
+		return u.String()
+	}
+	return ""
+}
+
+func (v *urlValue) Type() string {
+	return "url"
+}
+
+// textMarshalerValue is the value of a flag of a standard library type that implements
+// encoding.TextMarshaler and encoding.TextUnmarshaler, such as netip.Addr. Unlike String, their
+// MarshalText formats zero values as empty strings, which pflag does not show as default.
+type textMarshalerValue struct {
+	text interface {
+		encoding.TextMarshaler
+		encoding.TextUnmarshaler
+	}
+	desc string
+}
+
+func (v *textMarshalerValue) Set(s string) error {
+	return v.text.UnmarshalText([]byte(s))
+}
+
+func (v *textMarshalerValue) String() string {
+	text, _ := v.text.MarshalText() // cannot fail for netip types
+	return string(text)
+}
+
+func (v *textMarshalerValue) Type() string {
+	return v.desc
+}
+
+// countValue is the value of a flag with encoding:"count" that is bound to an environment
+// variable. The variable sets the count, e.g. FOO_VERBOSE=3, and the flag counts anew when given
+// on the command line, as flags take precedence.
+type countValue struct {
+	pflag.Value
+	field reflect.Value
+	env   bool // whether the count was set by the environment variable
+}
+
+func (v *countValue) Set(s string) error {
+	if v.env {
+		v.env = false
+		v.field.SetInt(0)
+	}
+	return v.Value.Set(s)
+}
+
+// setEnv sets the count to the value of an environment variable.
+func (v *countValue) setEnv(s string) error {
+	if _, err := strconv.ParseUint(s, 10, strconv.IntSize-1); err != nil {
+		return err
+	}
+	if err := v.Value.Set(s); err != nil {
+		return err
+	}
+	v.env = true
+	return nil
+}
+
+// addNegation adds the --no-<name> counterpart of the bool flag param to fs.
+func (b *binder) addNegation(fs *pflag.FlagSet, param *pflag.Flag) error {
+	if param.Value.Type() != "bool" {
+		return fmt.Errorf("requires a bool, got %s", param.Value.Type())
+	}
+	name := "no-" + param.Name
+	if fs.Lookup(name) != nil {
+		return fmt.Errorf("flag %q is already defined", name)
+	}
+	if owner := persistentOwner(b.cmd.Parent(), name); owner != nil {
+		return fmt.Errorf("flag %q shadows the persistent flag of %q", name, owner.CommandPath())
+	}
+	negation := &pflag.Flag{
+		Name:        name,
+		Usage:       fmt.Sprintf(DefaultMessages.Negation, param.Name),
+		Value:       &negatedValue{flag: param},
+		DefValue:    "false",
+		NoOptDefVal: "true",
+		Hidden:      param.Hidden,
+	}
+	if group, ok := param.Annotations[annotationHelpGroup]; ok {
+		setAnnotation(negation, annotationHelpGroup, group...)
+	}
+	fs.AddFlag(negation)
+	return nil
+}
+
+// negatedValue is the value of the --no-<name> flag of a negatable bool flag.
+type negatedValue struct {
+	flag *pflag.Flag
+	set  bool
+}
+
+func (v *negatedValue) Set(s string) error {
+	negate, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	if err := v.flag.Value.Set(strconv.FormatBool(!negate)); err != nil {
+		return err
+	}
+	v.flag.Changed = true
+	v.set = negate
+	return nil
+}
+
+func (v *negatedValue) String() string {
+	return strconv.FormatBool(v.set)
+}
+
+func (v *negatedValue) Type() string {
+	return "bool"
+}
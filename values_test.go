@@ -0,0 +1,220 @@
+package nicecmd
+
+import (
+	"errors"
+	"github.com/spf13/cobra"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata" // for systems without time zone database
+)
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want any
+	}{
+		{"42", 42},
+		{"true", true},
+		{"1m30s", 90 * time.Second},
+		{"a,b", []string{"a", "b"}},
+		{"k=v", map[string]string{"k": "v"}},
+		{"10.0.0.1", net.ParseIP("10.0.0.1")},
+	}
+	for _, tt := range tests {
+		got, err := ParseValue(reflect.TypeOf(tt.want), tt.in)
+		if err != nil {
+			t.Errorf("parse %q as %T: %v", tt.in, tt.want, err)
+		} else if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parse %q as %T: want %v, got %v", tt.in, tt.want, tt.want, got)
+		}
+	}
+
+	if _, err := ParseValue(reflect.TypeOf(0), "x"); err == nil || !strings.Contains(err.Error(), "invalid syntax") {
+		t.Errorf("expected parse error, got %v", err)
+	}
+	if _, err := ParseValue(reflect.TypeOf(struct{ A int }{}), "x"); err == nil || err.Error() != "type struct { A int } is not supported" {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+	if _, err := ParseValue(reflect.TypeOf(complex64(0)), "x"); err == nil || !strings.Contains(err.Error(), "unsupported field type") {
+		t.Errorf("expected unsupported type error, got %v", err)
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{42, "42"},
+		{90 * time.Second, "1m30s"},
+		{[]string{"a", "b"}, "[a,b]"},
+	}
+	for _, tt := range tests {
+		got, err := FormatValue(tt.in)
+		if err != nil {
+			t.Errorf("format %v: %v", tt.in, err)
+		} else if got != tt.want {
+			t.Errorf("format %v: want %q, got %q", tt.in, tt.want, got)
+		}
+	}
+	if _, err := FormatValue(nil); err == nil {
+		t.Error("expected error for nil")
+	}
+}
+
+func TestNegatable(t *testing.T) {
+	type NegatableConfig struct {
+		Color bool `flag:"negatable"`
+	}
+	cfg := NegatableConfig{Color: true}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron(nil)); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--no-color"}); err != nil || cfg.Color {
+		t.Errorf("expected --no-color to turn the flag off, got %t (%v)", cfg.Color, err)
+	}
+	if !cmd.Flags().Lookup("color").Changed {
+		t.Error("expected --no-color to mark the flag as changed")
+	}
+	if usage := cmd.Flags().FlagUsages(); !strings.Contains(usage, "--no-color   sets --color to false") {
+		t.Errorf("expected usage of the negation, got:\n%s", usage)
+	}
+
+	var bindErr *BindError
+	err := TryBindConfig("TEST", &cobra.Command{}, &struct {
+		Color string `flag:"negatable"`
+	}{}, WithEnviron(nil))
+	if !errors.As(err, &bindErr) {
+		t.Errorf("expected *BindError for negatable string, got %v", err)
+	}
+}
+
+func TestCountEnv(t *testing.T) {
+	type CountConfig struct {
+		Verbose int `param:"verbose,v" encoding:"count"`
+	}
+	for _, tc := range []struct {
+		env  string
+		args []string
+		want int
+	}{
+		{env: "3", want: 3},
+		{env: "3", args: []string{"-vv"}, want: 2},
+		{args: []string{"-vvv"}, want: 3},
+	} {
+		var cfg CountConfig
+		cmd := &cobra.Command{}
+		if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_VERBOSE=" + tc.env})); err != nil {
+			t.Fatalf("bind: %v", err)
+		}
+		if err := cmd.ParseFlags(tc.args); err != nil || cfg.Verbose != tc.want {
+			t.Errorf("env %q and args %q: expected %d, got %d (%v)", tc.env, tc.args, tc.want, cfg.Verbose, err)
+		}
+	}
+
+	var envErr *EnvError
+	err := TryBindConfig("TEST", &cobra.Command{}, &CountConfig{}, WithEnviron([]string{"TEST_VERBOSE=-1"}))
+	if !errors.As(err, &envErr) {
+		t.Errorf("expected *EnvError for negative count, got %v", err)
+	}
+}
+
+func TestLocation(t *testing.T) {
+	type ScheduleConfig struct {
+		Zone *time.Location
+	}
+	cfg := ScheduleConfig{Zone: time.UTC}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_ZONE=Europe/Berlin"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Zone == nil || cfg.Zone.String() != "Europe/Berlin" {
+		t.Errorf("expected Europe/Berlin from the environment, got %v", cfg.Zone)
+	}
+	if usage := cmd.Flags().FlagUsages(); !strings.Contains(usage, "--zone timezone") || !strings.Contains(usage, "(default UTC)") {
+		t.Errorf("expected timezone flag with default UTC, got:\n%s", usage)
+	}
+	if err := cmd.ParseFlags([]string{"--zone", "Mars/Olympus_Mons"}); err == nil {
+		t.Error("expected error for unknown time zone")
+	}
+	if got, err := FormatValue(cfg.Zone); err != nil || got != "Europe/Berlin" {
+		t.Errorf("expected zone name to be preserved, got %q, %v", got, err)
+	}
+
+	var envErr *EnvError
+	err := TryBindConfig("TEST", &cobra.Command{}, &ScheduleConfig{}, WithEnviron([]string{"TEST_ZONE=CEST"}))
+	if !errors.As(err, &envErr) {
+		t.Errorf("expected *EnvError for unknown time zone, got %v", err)
+	}
+}
+
+func TestURL(t *testing.T) {
+	type EndpointConfig struct {
+		API   *url.URL
+		Proxy url.URL
+	}
+	cfg := EndpointConfig{API: &url.URL{Scheme: "https", Host: "api.example.com", Path: "/v1"}}
+	cmd := &cobra.Command{}
+	if err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_PROXY=HTTP://proxy:3128"})); err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if cfg.Proxy.Scheme != "http" || cfg.Proxy.Host != "proxy:3128" {
+		t.Errorf("expected proxy URL from the environment, got %v", cfg.Proxy)
+	}
+	if usage := cmd.Flags().FlagUsages(); !strings.Contains(usage, "--api url") || !strings.Contains(usage, "(default https://api.example.com/v1)") {
+		t.Errorf("expected url flag with default, got:\n%s", usage)
+	}
+	if proxy := cmd.Flags().Lookup("proxy").Value.String(); proxy != "http://proxy:3128" {
+		t.Errorf("expected normalized URL, got %q", proxy)
+	}
+	if err := cmd.ParseFlags([]string{"--api", "api.example.com"}); err == nil || !strings.Contains(err.Error(), "missing scheme") {
+		t.Errorf("expected error for URL without scheme, got %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--api", ""}); err != nil || cfg.API != nil {
+		t.Errorf("expected empty value to reset the URL, got %v, %v", cfg.API, err)
+	}
+}
+
+func TestNetip(t *testing.T) {
+	type NetConfig struct {
+		Bind    netip.Addr
+		Allow   netip.Prefix
+		Listen  netip.AddrPort
+		Gateway netip.Addr
+	}
+	cfg := NetConfig{Listen: netip.MustParseAddrPort("[::1]:8080")}
+	cmd := &cobra.Command{}
+	err := TryBindConfig("TEST", cmd, &cfg, WithEnviron([]string{"TEST_BIND=10.0.0.1", "TEST_ALLOW=10.0.0.0/8"}))
+	if err != nil {
+		t.Fatalf("bind: %v", err)
+	}
+	if err := cmd.ParseFlags([]string{"--listen", "127.0.0.1:80"}); err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	want := NetConfig{
+		Bind:   netip.MustParseAddr("10.0.0.1"),
+		Allow:  netip.MustParsePrefix("10.0.0.0/8"),
+		Listen: netip.MustParseAddrPort("127.0.0.1:80"),
+	}
+	if cfg != want {
+		t.Errorf("expected %+v, got %+v", want, cfg)
+	}
+	usage := cmd.Flags().FlagUsages()
+	for _, s := range []string{"--bind ip", "--allow ipPrefix", "--listen ipPort", "(default [::1]:8080)"} {
+		if !strings.Contains(usage, s) {
+			t.Errorf("expected %q in usage, got:\n%s", s, usage)
+		}
+	}
+	if strings.Contains(usage, "invalid") {
+		t.Errorf("expected zero values not to be shown, got:\n%s", usage)
+	}
+	if err := cmd.ParseFlags([]string{"--gateway", "10.0.0.256"}); err == nil {
+		t.Error("expected error for invalid address")
+	}
+}
@@ -0,0 +1,119 @@
+// Package vault reads configuration values, typically those of secret flags, from the KV secrets
+// engine of HashiCorp Vault. It talks to Vault's HTTP API directly, so that programs do not need to
+// depend on Vault's client library.
+//
+// Use it as a source of a nicecmd command:
+//
+//	cmd := nicecmd.Command("APP", run, cobra.Command{Use: "app"}, Config{},
+//		nicecmd.WithConfigSource(&vault.Source{Path: "secret/data/app"}))
+//
+// The values of the secret are applied to the flags with matching names before the command's
+// hooks run, unless the flags were set on the command line or via environment variables.
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Source reads a single secret of Vault's KV secrets engine, version 1 or 2.
+type Source struct {
+	// Address of the Vault server, e.g. "https://vault.example.com:8200". Defaults to $VAULT_ADDR.
+	Address string
+
+	// Token to authenticate with. Defaults to $VAULT_TOKEN.
+	Token string
+
+	// Namespace of Vault Enterprise, if any. Defaults to $VAULT_NAMESPACE.
+	Namespace string
+
+	// Path of the secret as used in the API, e.g. "secret/data/app" for version 2 of the engine
+	// mounted at "secret", or "secret/app" for version 1.
+	Path string
+
+	// Client for requests to Vault. Defaults to a client with a timeout of 10 seconds.
+	Client *http.Client
+}
+
+var defaultClient = &http.Client{Timeout: 10 * time.Second}
+
+// Name returns the path of the secret, prefixed with "vault:".
+func (s *Source) Name() string {
+	return "vault:" + s.Path
+}
+
+// Load reads the secret.
+func (s *Source) Load(ctx context.Context) (map[string]any, error) {
+	address, token, namespace := s.Address, s.Token, s.Namespace
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if namespace == "" {
+		namespace = os.Getenv("VAULT_NAMESPACE")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("no address given, and VAULT_ADDR is not set")
+	}
+	client := s.Client
+	if client == nil {
+		client = defaultClient
+	}
+
+	url := strings.TrimSuffix(address, "/") + "/v1/" + strings.TrimPrefix(s.Path, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("X-Vault-Token", token)
+	}
+	if namespace != "" {
+		req.Header.Set("X-Vault-Namespace", namespace)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s%s", resp.Status, vaultErrors(body))
+	}
+
+	var secret struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, err
+	}
+	// Version 2 wraps the values along with metadata
+	if data, ok := secret.Data["data"].(map[string]any); ok {
+		if _, ok := secret.Data["metadata"]; ok {
+			return data, nil
+		}
+	}
+	return secret.Data, nil
+}
+
+// vaultErrors formats the errors of an error response of Vault, if any.
+func vaultErrors(body []byte) string {
+	var resp struct {
+		Errors []string `json:"errors"`
+	}
+	if json.Unmarshal(body, &resp) != nil || len(resp.Errors) == 0 {
+		return ""
+	}
+	return ": " + strings.Join(resp.Errors, "; ")
+}
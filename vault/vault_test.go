@@ -0,0 +1,90 @@
+package vault_test
+
+import (
+	"context"
+	"github.com/mologie/nicecmd"
+	"github.com/mologie/nicecmd/vault"
+	"github.com/spf13/cobra"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newVault(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			_, _ = w.Write([]byte(`{"data": {"data": {"password": "hunter2"}, "metadata": {"version": 1}}}`))
+		case "/v1/kv/app":
+			_, _ = w.Write([]byte(`{"data": {"password": "hunter3"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"errors": []}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSource_Load(t *testing.T) {
+	srv := newVault(t)
+	for path, want := range map[string]string{"secret/data/app": "hunter2", "kv/app": "hunter3"} {
+		src := &vault.Source{Address: srv.URL, Token: "root", Path: path}
+		data, err := src.Load(context.Background())
+		if err != nil {
+			t.Errorf("load %s: %v", path, err)
+		} else if data["password"] != want {
+			t.Errorf("expected %q from %s, got %v", want, path, data)
+		}
+	}
+
+	src := &vault.Source{Address: srv.URL, Token: "wrong", Path: "secret/data/app"}
+	if _, err := src.Load(context.Background()); err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("expected error of Vault, got %v", err)
+	}
+}
+
+func TestSource_Environment(t *testing.T) {
+	srv := newVault(t)
+	t.Setenv("VAULT_ADDR", srv.URL)
+	t.Setenv("VAULT_TOKEN", "root")
+	src := &vault.Source{Path: "kv/app"}
+	if data, err := src.Load(context.Background()); err != nil || data["password"] != "hunter3" {
+		t.Errorf("expected address and token from environment, got %v, %v", data, err)
+	}
+
+	t.Setenv("VAULT_ADDR", "")
+	if _, err := src.Load(context.Background()); err == nil {
+		t.Error("expected error without address")
+	}
+}
+
+func TestSource_Command(t *testing.T) {
+	srv := newVault(t)
+	type Config struct {
+		Password string `flag:"secret"`
+	}
+	cmd := nicecmd.Command("APP", nicecmd.RunFuncs[Config]{}, cobra.Command{Use: "app"}, Config{},
+		nicecmd.WithConfigSource(&vault.Source{Address: srv.URL, Token: "root", Path: "secret/data/app"}))
+	cfg, err := nicecmd.Resolve[Config](cmd, nil, map[string]string{})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Password != "hunter2" {
+		t.Errorf("expected secret from Vault, got %q", cfg.Password)
+	}
+
+	cfg, err = nicecmd.Resolve[Config](cmd, nil, map[string]string{"APP_PASSWORD": "env"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.Password != "env" {
+		t.Errorf("expected environment to take precedence, got %q", cfg.Password)
+	}
+}
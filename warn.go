@@ -0,0 +1,43 @@
+package nicecmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"slices"
+)
+
+// WarningHandler receives all warnings emitted through Warn, including nicecmd's own. When nil,
+// warnings are printed to the command's stderr with a "Warning:" prefix, analogous to Cobra's
+// "Error:" prefix. The prefix can be localized via DefaultMessages.
+var WarningHandler func(cmd *cobra.Command, msg string)
+
+// Warn emits a warning for cmd. Use this for notices that should not abort the command, so that
+// they end up in the same place and format as the warnings of nicecmd itself.
+func Warn(cmd *cobra.Command, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if WarningHandler != nil {
+		WarningHandler(cmd, msg)
+	} else {
+		cmd.PrintErrln(DefaultMessages.Warning, msg)
+	}
+}
+
+// warnings collects warnings while a command is being set up, so that they are emitted in one
+// go after binding. Duplicate messages are only emitted once.
+type warnings struct {
+	msgs []string
+}
+
+func (w *warnings) add(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	if !slices.Contains(w.msgs, msg) {
+		w.msgs = append(w.msgs, msg)
+	}
+}
+
+func (w *warnings) flush(cmd *cobra.Command) {
+	for _, msg := range w.msgs {
+		Warn(cmd, "%s", msg)
+	}
+	w.msgs = nil
+}
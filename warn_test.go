@@ -0,0 +1,55 @@
+package nicecmd
+
+import (
+	"bytes"
+	"github.com/spf13/cobra"
+	"slices"
+	"testing"
+)
+
+func TestWarn_Stderr(t *testing.T) {
+	cmd := &cobra.Command{}
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+	Warn(cmd, "foo %d", 42)
+	if out := stderr.String(); out != "Warning: foo 42\n" {
+		t.Errorf("unexpected warning output: %q", out)
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected no output on stdout, got %q", stdout.String())
+	}
+}
+
+func TestWarn_Handler(t *testing.T) {
+	var got []string
+	WarningHandler = func(cmd *cobra.Command, msg string) {
+		got = append(got, msg)
+	}
+	defer func() { WarningHandler = nil }()
+
+	var conf struct {
+		Foo string `flag:"bogus"`
+		Bar string `flag:"persistent,bogus"`
+		Baz string `flag:"bogus"`
+	}
+	BindConfig("TEST", &cobra.Command{}, &conf)
+	want := []string{
+		`unknown flag option "bogus" for "foo"`,
+		`unknown flag option "bogus" for "bar"`,
+		`unknown flag option "bogus" for "baz"`,
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected warnings, want %q, got %q", want, got)
+	}
+}
+
+func TestWarnings_Dedup(t *testing.T) {
+	var w warnings
+	w.add("foo")
+	w.add("bar %d", 1)
+	w.add("foo")
+	if !slices.Equal(w.msgs, []string{"foo", "bar 1"}) {
+		t.Errorf("unexpected warnings: %q", w.msgs)
+	}
+}
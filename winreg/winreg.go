@@ -0,0 +1,50 @@
+// Package winreg reads configuration values from a key of the Windows registry, e.g. for tools
+// that are configured via group policies. On other platforms, loading fails with
+// errors.ErrUnsupported.
+//
+// Use it as a source of a nicecmd command:
+//
+//	cmd := nicecmd.Command("APP", run, cobra.Command{Use: "app"}, Config{},
+//		nicecmd.WithConfigSource(winreg.Machine(`Software\App`)),
+//		nicecmd.WithConfigSource(winreg.User(`Software\App`)))
+//
+// Value names are the names of environment variables without the prefix, e.g. LOG_LEVEL for
+// APP_LOG_LEVEL, and match flags like the keys of configuration files. Strings and numbers are
+// applied as they are, and multi-strings to slice flags. Sub-keys are not read.
+package winreg
+
+// Root identifies a predefined registry key.
+type Root int
+
+const (
+	LocalMachine Root = iota // HKEY_LOCAL_MACHINE
+	CurrentUser              // HKEY_CURRENT_USER
+)
+
+func (r Root) String() string {
+	if r == CurrentUser {
+		return "HKCU"
+	}
+	return "HKLM"
+}
+
+// Source reads the values of a registry key. A key that does not exist has no values.
+type Source struct {
+	Root Root
+	Path string // of the key below Root, e.g. `Software\App`
+}
+
+// Machine returns a source for the given key below HKEY_LOCAL_MACHINE.
+func Machine(path string) *Source {
+	return &Source{Root: LocalMachine, Path: path}
+}
+
+// User returns a source for the given key below HKEY_CURRENT_USER.
+func User(path string) *Source {
+	return &Source{Root: CurrentUser, Path: path}
+}
+
+// Name returns the full path of the key, e.g. `HKLM\Software\App`.
+func (s *Source) Name() string {
+	return s.Root.String() + `\` + s.Path
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+)
+
+// Load fails with errors.ErrUnsupported, as there is no registry.
+func (s *Source) Load(ctx context.Context) (map[string]any, error) {
+	return nil, errors.ErrUnsupported
+}
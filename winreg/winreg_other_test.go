@@ -0,0 +1,15 @@
+//go:build !windows
+
+package winreg
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSource_Load_Unsupported(t *testing.T) {
+	if _, err := Machine(`Software\App`).Load(context.Background()); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
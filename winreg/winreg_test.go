@@ -0,0 +1,14 @@
+package winreg
+
+import (
+	"testing"
+)
+
+func TestSource_Name(t *testing.T) {
+	if got := Machine(`Software\App`).Name(); got != `HKLM\Software\App` {
+		t.Errorf("unexpected name %q", got)
+	}
+	if got := User(`Software\App`).Name(); got != `HKCU\Software\App` {
+		t.Errorf("unexpected name %q", got)
+	}
+}
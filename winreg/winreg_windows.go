@@ -0,0 +1,108 @@
+package winreg
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+var procRegEnumValueW = syscall.NewLazyDLL("advapi32.dll").NewProc("RegEnumValueW")
+
+const errorNoMoreItems syscall.Errno = 259 // ERROR_NO_MORE_ITEMS, not defined by package syscall
+
+// Load reads all values of the key.
+func (s *Source) Load(ctx context.Context) (map[string]any, error) {
+	root := syscall.Handle(syscall.HKEY_LOCAL_MACHINE)
+	if s.Root == CurrentUser {
+		root = syscall.HKEY_CURRENT_USER
+	}
+	path, err := syscall.UTF16PtrFromString(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	var key syscall.Handle
+	if err := syscall.RegOpenKeyEx(root, path, 0, syscall.KEY_READ, &key); errors.Is(err, syscall.ERROR_FILE_NOT_FOUND) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer func() { _ = syscall.RegCloseKey(key) }()
+
+	var count, maxNameLen, maxDataLen uint32
+	if err := syscall.RegQueryInfoKey(key, nil, nil, nil, nil, nil, nil, &count, &maxNameLen, &maxDataLen, nil, nil); err != nil {
+		return nil, err
+	}
+	doc := make(map[string]any, count)
+	name := make([]uint16, maxNameLen+1)
+	data := make([]byte, maxDataLen)
+	for i := uint32(0); i < count; i++ {
+		nameLen, dataLen := uint32(len(name)), uint32(len(data))
+		var typ uint32
+		ret, _, _ := procRegEnumValueW.Call(uintptr(key), uintptr(i),
+			uintptr(unsafe.Pointer(&name[0])), uintptr(unsafe.Pointer(&nameLen)), 0,
+			uintptr(unsafe.Pointer(&typ)), uintptr(unsafe.Pointer(unsafe.SliceData(data))), uintptr(unsafe.Pointer(&dataLen)))
+		if errno := syscall.Errno(ret); errno == errorNoMoreItems {
+			break
+		} else if errno != 0 {
+			return nil, errno
+		}
+		if value, ok := decode(typ, data[:dataLen]); ok {
+			doc[syscall.UTF16ToString(name[:nameLen])] = value
+		}
+	}
+	return doc, nil
+}
+
+// decode converts registry data to a value of a configuration document. Types that have no
+// representation as flag values, such as binary data, are skipped.
+func decode(typ uint32, data []byte) (any, bool) {
+	switch typ {
+	case syscall.REG_SZ, syscall.REG_EXPAND_SZ:
+		return utf16String(data), true
+	case syscall.REG_MULTI_SZ:
+		var values []any
+		for _, s := range splitMulti(utf16s(data)) {
+			values = append(values, s)
+		}
+		return values, true
+	case syscall.REG_DWORD:
+		if len(data) >= 4 {
+			return strconv.FormatUint(uint64(binary.LittleEndian.Uint32(data)), 10), true
+		}
+	case syscall.REG_QWORD:
+		if len(data) >= 8 {
+			return strconv.FormatUint(binary.LittleEndian.Uint64(data), 10), true
+		}
+	}
+	return nil, false
+}
+
+func utf16s(data []byte) []uint16 {
+	u := make([]uint16, len(data)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(data[2*i:])
+	}
+	return u
+}
+
+func utf16String(data []byte) string {
+	return syscall.UTF16ToString(utf16s(data))
+}
+
+// splitMulti splits the NUL-separated strings of REG_MULTI_SZ.
+func splitMulti(u []uint16) (values []string) {
+	start := 0
+	for i, c := range u {
+		if c == 0 {
+			if i == start {
+				break // terminating empty string
+			}
+			values = append(values, syscall.UTF16ToString(u[start:i]))
+			start = i + 1
+		}
+	}
+	return values
+}
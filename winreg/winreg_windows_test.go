@@ -0,0 +1,47 @@
+package winreg
+
+import (
+	"context"
+	"reflect"
+	"syscall"
+	"testing"
+	"unicode/utf16"
+)
+
+func encodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	data := make([]byte, 2*len(u))
+	for i, c := range u {
+		data[2*i], data[2*i+1] = byte(c), byte(c>>8)
+	}
+	return data
+}
+
+func TestDecode(t *testing.T) {
+	for _, tc := range []struct {
+		typ  uint32
+		data []byte
+		want any
+	}{
+		{syscall.REG_SZ, encodeUTF16("debug\x00"), "debug"},
+		{syscall.REG_EXPAND_SZ, encodeUTF16("%TEMP%\x00"), "%TEMP%"},
+		{syscall.REG_MULTI_SZ, encodeUTF16("a\x00b\x00\x00"), []any{"a", "b"}},
+		{syscall.REG_DWORD, []byte{0x90, 0x1f, 0, 0}, "8080"},
+		{syscall.REG_QWORD, []byte{1, 0, 0, 0, 0, 0, 0, 0}, "1"},
+	} {
+		got, ok := decode(tc.typ, tc.data)
+		if !ok || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("decode(%d, %v): expected %v, got %v", tc.typ, tc.data, tc.want, got)
+		}
+	}
+	if _, ok := decode(syscall.REG_BINARY, []byte{1}); ok {
+		t.Error("expected binary data to be skipped")
+	}
+}
+
+func TestSource_Load_Missing(t *testing.T) {
+	doc, err := User(`Software\nicecmd-test-missing`).Load(context.Background())
+	if err != nil || len(doc) != 0 {
+		t.Errorf("expected no values for missing key, got %v, %v", doc, err)
+	}
+}
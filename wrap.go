@@ -2,7 +2,9 @@ package nicecmd
 
 import (
 	"github.com/spf13/cobra"
+	"io"
 	"os"
+	"time"
 )
 
 var osExitOrTestHook = os.Exit
@@ -17,12 +19,6 @@ type RunFuncs[T any] struct {
 	PersistentPostRun RunE[T]
 }
 
-func init() {
-	// Opinionated default: We'd want all parent hooks to run by default. The user can still
-	// disable this after the fact by changing Cobra's global setting back to false.
-	cobra.EnableTraverseRunHooks = true
-}
-
 // PersistentPreRun is a convenience function to create a RunFuncs with only the PersistentPreRun function set.
 func PersistentPreRun[T any](f func(cfg T, cmd *cobra.Command, args []string) error) RunFuncs[T] {
 	return RunFuncs[T]{PersistentPreRun: f}
@@ -33,43 +29,201 @@ func Run[T any](f func(cfg T, cmd *cobra.Command, args []string) error) RunFuncs
 	return RunFuncs[T]{Run: f}
 }
 
-func Command[T any](envPrefix string, run RunFuncs[T], cmd cobra.Command, cfg T) *cobra.Command {
-	cmd.PersistentPreRunE = passCfg(&cfg, run.PersistentPreRun)
-	cmd.PreRunE = passCfg(&cfg, run.PreRun)
-	cmd.RunE = passCfg(&cfg, run.Run)
-	cmd.PostRunE = passCfg(&cfg, run.PostRun)
-	cmd.PersistentPostRunE = passCfg(&cfg, run.PersistentPostRun)
+// Command creates a cobra.Command from the template cmd, which invokes the functions of run with a
+// copy of cfg. The fields of cfg are bound to flags and environment variables via BindConfig.
+//
+// Command panics if cfg cannot be bound. If environment variables have invalid values, then it
+// prints the errors and the command's usage and exits the program.
+func Command[T any](envPrefix string, run RunFuncs[T], cmd cobra.Command, cfg T, opts ...Option) *cobra.Command {
+	c, envErrs, err := command(envPrefix, run, cmd, cfg, newOptions(opts), envSource{})
+	if err != nil {
+		panic(err.Error())
+	}
+	if len(envErrs) != 0 {
+		for _, envErr := range envErrs {
+			c.Println(c.ErrPrefix(), envErr)
+		}
+		_ = c.Usage()
+		osExitOrTestHook(1)
+		return nil
+	}
+	return c
+}
+
+// TryCommand is like Command, but returns an error instead of panicking or exiting. The error is a
+// *BindError if the command cannot be set up. If environment variables have invalid values, then
+// the error consists of joined *EnvError, and the command is returned as well so that its usage can
+// be shown.
+func TryCommand[T any](envPrefix string, run RunFuncs[T], cmd cobra.Command, cfg T, opts ...Option,
+) (*cobra.Command, error) {
+	c, envErrs, err := command(envPrefix, run, cmd, cfg, newOptions(opts), envSource{})
+	if err != nil {
+		return nil, err
+	}
+	return c, joinEnvErrors(envErrs)
+}
+
+func command[T any](envPrefix string, run RunFuncs[T], cmd cobra.Command, cfg T, o options, env envSource,
+) (*cobra.Command, []*EnvError, error) {
+	template, defaults := cmd, cfg
+	envErrs, err := setupCommand(envPrefix, &cmd, &cfg, o, env)
+	if err != nil {
+		return nil, nil, err
+	}
+	setHooks(&cmd, &cfg, run, o)
+	lookupBinding(&cmd).rebuild = func(env envSource, stdout, stderr io.Writer) (*cobra.Command, []*EnvError, error) {
+		cmd := template
+		if stdout != nil {
+			cmd.SetOut(stdout)
+		}
+		if stderr != nil {
+			cmd.SetErr(stderr)
+		}
+		return command(envPrefix, run, cmd, defaults, o, env)
+	}
+	return &cmd, envErrs, nil
+}
+
+// setHooks sets the hooks of cmd to invoke the functions of run with a copy of cfg. cmd must be
+// bound already, as its persistent hooks are kept with its binding.
+func setHooks[T any](cmd *cobra.Command, cfg *T, run RunFuncs[T], o options) {
+	// Opinionated default: We'd want all parent hooks to run, as with Cobra's
+	// EnableTraverseRunHooks, but without changing that setting for unrelated commands.
+	b := lookupBinding(cmd)
+	b.hooked = true
+	b.persistentPreRun = passCfg(cfg, HookPersistentPreRun, run.PersistentPreRun, o)
+	b.persistentPostRun = passCfg(cfg, HookPersistentPostRun, run.PersistentPostRun, o)
+	cmd.PersistentPreRunE = chainPersistentPreRun(cmd)
+	cmd.PreRunE = passCfg(cfg, HookPreRun, run.PreRun, o)
+	cmd.RunE = passCfg(cfg, HookRun, run.Run, o)
+	cmd.PostRunE = passCfg(cfg, HookPostRun, run.PostRun, o)
+	cmd.PersistentPostRunE = chainPersistentPostRun(cmd)
+}
 
+// setupCommand applies nicecmd's defaults to cmd and binds cfg to it.
+func setupCommand(envPrefix string, cmd *cobra.Command, cfg any, o options, env envSource) ([]*EnvError, error) {
 	// Opinionated defaults: Local flags should just work, and the user is expected to provide a
 	// proper "Use" line for the command that suggests where flags should go.
 	if cmd.Use == "" {
-		panic("use line must be set, and should include all non-global flags")
+		return nil, &BindError{Msg: "use line must be set, and should include all non-global flags"}
 	}
+
 	cmd.TraverseChildren = true
 	cmd.DisableAutoGenTag = true
 	cmd.DisableFlagsInUseLine = true
 
-	// Opinionated default: Accept no args unless those were explicitly allowed by the user.
-	// pflag's default is to accept arbitrary args by default.
+	envErrs, err := bindConfig(envPrefix, cmd, cfg, o, env)
+
+	// Opinionated default: Accept no args unless those were explicitly allowed by the user, or
+	// bound via arg tags. pflag's default is to accept arbitrary args by default.
 	if cmd.Args == nil {
 		cmd.Args = cobra.NoArgs
 	}
+	if err == nil && o.config.enabled() {
+		err = setupConfig(cmd, o.config)
+	}
+	return envErrs, err
+}
 
-	if BindConfig(envPrefix, &cmd, &cfg) {
-		return &cmd
+func passCfg[T any](cfg *T, kind HookKind, f RunE[T], o options) func(cmd *cobra.Command, args []string) error {
+	if f == nil {
+		return nil
+	} else if o.hookObserver != nil {
+		return func(cmd *cobra.Command, args []string) error {
+			start := time.Now()
+			err := f(*cfg, cmd, args)
+			o.hookObserver(HookEvent{Command: cmd, Hook: kind, Duration: time.Since(start), Err: err})
+			return err
+		}
 	} else {
-		_ = cmd.Usage()
-		osExitOrTestHook(1)
+		return func(cmd *cobra.Command, args []string) error {
+			return f(*cfg, cmd, args)
+		}
+	}
+}
+
+// chainPersistentPreRun returns the persistent pre-run hook of owner. It applies the
+// configuration files of owner and its parents, and runs the persistent pre-run hooks of all of
+// owner's parents, starting at the root, before the one of owner. Without EnableTraverseRunHooks,
+// Cobra only runs the hook closest to the executed command, which does not know about the hooks
+// of its parents unless they were created by nicecmd as well. Likewise, nothing runs this hook if
+// a plain Cobra command between owner and the executed command has a persistent hook.
+func chainPersistentPreRun(owner *cobra.Command) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if cobra.EnableTraverseRunHooks {
+			// Cobra runs the hooks of the parents, which apply their configuration files themselves
+			if err := applyConfigFiles(owner, cmd); err != nil {
+				return err
+			}
+			markEnvChanged(cmd)
+			return runHook(ownPersistentPreRun(owner), cmd, args)
+		}
+		if err := applyConfigFlags(cmd); err != nil {
+			return err
+		}
+		var chain []*cobra.Command
+		for p := owner; p != nil; p = p.Parent() {
+			chain = append(chain, p)
+		}
+		for i := len(chain) - 1; i >= 0; i-- {
+			if err := runHook(ownPersistentPreRun(chain[i]), cmd, args); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// chainPersistentPostRun is like chainPersistentPreRun, but runs the persistent post-run hooks of
+// owner and then its parents, ending at the root.
+func chainPersistentPostRun(owner *cobra.Command) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		for p := owner; p != nil; p = p.Parent() {
+			if err := runHook(ownPersistentPostRun(p), cmd, args); err != nil {
+				return err
+			}
+			if cobra.EnableTraverseRunHooks {
+				break // Cobra runs the hooks of the parents
+			}
+		}
 		return nil
 	}
 }
 
-func passCfg[T any](cfg *T, f RunE[T]) func(cmd *cobra.Command, args []string) error {
-	if f != nil {
+// ownPersistentPreRun returns the persistent pre-run hook of cmd itself, without those of its
+// parents that nicecmd chains to it, or nil if it has none.
+func ownPersistentPreRun(cmd *cobra.Command) func(cmd *cobra.Command, args []string) error {
+	if b := lookupBinding(cmd); b != nil && b.hooked {
+		return b.persistentPreRun
+	} else if cmd.PersistentPreRunE != nil {
+		return cmd.PersistentPreRunE
+	} else if hook := cmd.PersistentPreRun; hook != nil {
 		return func(cmd *cobra.Command, args []string) error {
-			return f(*cfg, cmd, args)
+			hook(cmd, args)
+			return nil
 		}
-	} else {
+	}
+	return nil
+}
+
+// ownPersistentPostRun is like ownPersistentPreRun, but for the persistent post-run hook.
+func ownPersistentPostRun(cmd *cobra.Command) func(cmd *cobra.Command, args []string) error {
+	if b := lookupBinding(cmd); b != nil && b.hooked {
+		return b.persistentPostRun
+	} else if cmd.PersistentPostRunE != nil {
+		return cmd.PersistentPostRunE
+	} else if hook := cmd.PersistentPostRun; hook != nil {
+		return func(cmd *cobra.Command, args []string) error {
+			hook(cmd, args)
+			return nil
+		}
+	}
+	return nil
+}
+
+func runHook(hook func(cmd *cobra.Command, args []string) error, cmd *cobra.Command, args []string) error {
+	if hook == nil {
 		return nil
 	}
+	return hook(cmd, args)
 }
@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 	"os"
 	"reflect"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -135,3 +136,161 @@ func TestCommand_UsageAndExitOnBadConfig(t *testing.T) {
 		t.Errorf("expected Command to print usage on invalid env, but got output: %v", out)
 	}
 }
+
+func TestTryCommand(t *testing.T) {
+	var bindErr *BindError
+	if _, err := TryCommand("", Run(trivialRun), cobra.Command{}, TrivialConf{}); !errors.As(err, &bindErr) {
+		t.Errorf("expected BindError for missing use line, got %v", err)
+	}
+
+	if err := os.Setenv("NICECMD_TRYCMD_BAR", "bar"); err != nil {
+		t.Errorf("setenv: %v", err)
+		return
+	}
+	defer func() { _ = os.Unsetenv("NICECMD_TRYCMD_BAR") }()
+	cmd, err := TryCommand("NICECMD_TRYCMD", Run(trivialRun), cobra.Command{Use: "test"}, TrivialConf{})
+	var envErr *EnvError
+	if !errors.As(err, &envErr) || envErr.Name != "NICECMD_TRYCMD_BAR" {
+		t.Errorf("expected EnvError for NICECMD_TRYCMD_BAR, got %v", err)
+	}
+	if cmd == nil {
+		t.Error("expected command to be returned along with environment errors")
+	}
+}
+
+func TestCommand_ParentHooks(t *testing.T) {
+	for _, traverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("traverse=%t", traverse), func(t *testing.T) {
+			defer func(prev bool) { cobra.EnableTraverseRunHooks = prev }(cobra.EnableTraverseRunHooks)
+			cobra.EnableTraverseRunHooks = traverse
+
+			var calls []string
+			hooks := func(name string) RunFuncs[TrivialConf] {
+				record := func(hook string) RunE[TrivialConf] {
+					return func(cfg TrivialConf, cmd *cobra.Command, args []string) error {
+						calls = append(calls, name+"."+hook)
+						return nil
+					}
+				}
+				return RunFuncs[TrivialConf]{
+					PersistentPreRun:  record("pre"),
+					Run:               record("run"),
+					PersistentPostRun: record("post"),
+				}
+			}
+			root := Command("TEST", hooks("root"), cobra.Command{Use: "root"}, TrivialConf{})
+			mid := Command("TEST_MID", hooks("mid"), cobra.Command{Use: "mid"}, TrivialConf{})
+			plain := &cobra.Command{Use: "plain"}
+			leaf := Command("TEST_LEAF", hooks("leaf"), cobra.Command{Use: "leaf"}, TrivialConf{})
+			root.AddCommand(mid)
+			mid.AddCommand(plain)
+			plain.AddCommand(leaf)
+
+			root.SetArgs([]string{"mid", "plain", "leaf"})
+			if err := root.Execute(); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			want := []string{"root.pre", "mid.pre", "leaf.pre", "leaf.run", "leaf.post", "mid.post", "root.post"}
+			if !slices.Equal(calls, want) {
+				t.Errorf("unexpected hook order:\nwant %q\ngot  %q", want, calls)
+			}
+			if cobra.EnableTraverseRunHooks != traverse {
+				t.Error("expected Cobra's global setting to be left alone")
+			}
+		})
+	}
+}
+
+func TestCommand_PlainParentHooks(t *testing.T) {
+	for _, traverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("traverse=%t", traverse), func(t *testing.T) {
+			defer func(prev bool) { cobra.EnableTraverseRunHooks = prev }(cobra.EnableTraverseRunHooks)
+			cobra.EnableTraverseRunHooks = traverse
+
+			var calls []string
+			record := func(name string) RunE[TrivialConf] {
+				return func(cfg TrivialConf, cmd *cobra.Command, args []string) error {
+					calls = append(calls, name)
+					return nil
+				}
+			}
+			root := Command("TEST", RunFuncs[TrivialConf]{
+				PersistentPreRun:  record("root.pre"),
+				PersistentPostRun: record("root.post"),
+			}, cobra.Command{Use: "root"}, TrivialConf{})
+			mid := &cobra.Command{
+				Use: "mid",
+				PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+					calls = append(calls, "mid.pre")
+					return nil
+				},
+				PersistentPostRun: func(cmd *cobra.Command, args []string) {
+					calls = append(calls, "mid.post")
+				},
+			}
+			leaf := Command("TEST_LEAF", Run(record("leaf.run")), cobra.Command{Use: "leaf"}, TrivialConf{})
+			root.AddCommand(mid)
+			mid.AddCommand(leaf)
+
+			root.SetArgs([]string{"mid", "leaf"})
+			if err := root.Execute(); err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			want := []string{"root.pre", "mid.pre", "leaf.run", "mid.post", "root.post"}
+			if !slices.Equal(calls, want) {
+				t.Errorf("unexpected hook order:\nwant %q\ngot  %q", want, calls)
+			}
+		})
+	}
+}
+
+func TestCommand_PlainChildHooks(t *testing.T) {
+	for _, traverse := range []bool{false, true} {
+		t.Run(fmt.Sprintf("traverse=%t", traverse), func(t *testing.T) {
+			defer func(prev bool) { cobra.EnableTraverseRunHooks = prev }(cobra.EnableTraverseRunHooks)
+			cobra.EnableTraverseRunHooks = traverse
+
+			var calls []string
+			record := func(name string) func(cmd *cobra.Command, args []string) {
+				return func(cmd *cobra.Command, args []string) {
+					calls = append(calls, name)
+				}
+			}
+			root := Command("TEST", PersistentPreRun(func(cfg TrivialConf, cmd *cobra.Command, args []string) error {
+				calls = append(calls, "root.pre")
+				return nil
+			}), cobra.Command{Use: "root"}, TrivialConf{})
+			hooked := &cobra.Command{Use: "hooked", PersistentPreRun: record("hooked.pre"), Run: record("hooked.run")}
+			bare := &cobra.Command{Use: "bare", Run: record("bare.run")}
+			leaf := Command("TEST_LEAF", Run(func(cfg TrivialConf, cmd *cobra.Command, args []string) error {
+				calls = append(calls, "leaf.run")
+				return nil
+			}), cobra.Command{Use: "leaf"}, TrivialConf{})
+			root.AddCommand(hooked, bare)
+			hooked.AddCommand(leaf)
+
+			for _, tc := range []struct {
+				args []string
+				want []string
+			}{
+				{[]string{"bare"}, []string{"root.pre", "bare.run"}},
+				{[]string{"hooked", "leaf"}, []string{"root.pre", "hooked.pre", "leaf.run"}},
+				// The hook of a plain child replaces those of its parents, as documented, unless
+				// Cobra runs them itself
+				{[]string{"hooked"}, map[bool][]string{
+					false: {"hooked.pre", "hooked.run"},
+					true:  {"root.pre", "hooked.pre", "hooked.run"},
+				}[traverse]},
+			} {
+				calls = nil
+				root.SetArgs(tc.args)
+				if err := root.Execute(); err != nil {
+					t.Fatalf("execute %q: %v", tc.args, err)
+				}
+				if !slices.Equal(calls, tc.want) {
+					t.Errorf("unexpected hooks for %q:\nwant %q\ngot  %q", tc.args, tc.want, calls)
+				}
+			}
+		})
+	}
+}